@@ -0,0 +1,40 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dominika232323/token-transfer-api/graph/model"
+	"github.com/dominika232323/token-transfer-api/internal/audit"
+)
+
+func parseOptionalID(name string, id *string) (int64, error) {
+	if id == nil {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseInt(*id, 10, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid %s id %q", name, *id)
+	}
+
+	return value, nil
+}
+
+func toAuditChainReport(report *audit.Report) *model.AuditChainReport {
+	result := &model.AuditChainReport{
+		Valid:   report.Valid,
+		Checked: report.Checked,
+	}
+
+	if report.FirstBrokenID != nil {
+		firstBrokenID := strconv.FormatInt(*report.FirstBrokenID, 10)
+		result.FirstBrokenID = &firstBrokenID
+	}
+
+	if report.Reason != "" {
+		result.Reason = &report.Reason
+	}
+
+	return result
+}
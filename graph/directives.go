@@ -0,0 +1,25 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/dominika232323/token-transfer-api/internal/auth"
+)
+
+func NewDirectives() DirectiveRoot {
+	return DirectiveRoot{
+		Admin: Admin,
+	}
+}
+
+// Admin rejects fields marked with @admin unless the request was
+// authenticated with the admin token.
+func Admin(ctx context.Context, obj any, next graphql.Resolver) (any, error) {
+	if !auth.IsAdmin(ctx) {
+		return nil, fmt.Errorf("admin access required")
+	}
+
+	return next(ctx)
+}
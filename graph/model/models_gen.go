@@ -2,8 +2,224 @@
 
 package model
 
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type AuditChainReport struct {
+	Valid         bool    `json:"valid"`
+	Checked       int64   `json:"checked"`
+	FirstBrokenID *string `json:"first_broken_id,omitempty"`
+	Reason        *string `json:"reason,omitempty"`
+}
+
 type Mutation struct {
 }
 
 type Query struct {
 }
+
+type ReconciliationReport struct {
+	GeneratedAt       time.Time      `json:"generated_at"`
+	WalletsChecked    int64          `json:"wallets_checked"`
+	WalletDrifts      []*WalletDrift `json:"wallet_drifts"`
+	TotalSupply       int64          `json:"total_supply"`
+	TotalBalances     int64          `json:"total_balances"`
+	SupplyDrift       int64          `json:"supply_drift"`
+	UnbalancedEntries []string       `json:"unbalanced_entries"`
+	Reconciled        bool           `json:"reconciled"`
+}
+
+type Subscription struct {
+}
+
+type Transfer struct {
+	ID          string    `json:"id"`
+	FromAddress string    `json:"from_address"`
+	ToAddress   string    `json:"to_address"`
+	Amount      int64     `json:"amount"`
+	ReversalOf  *string   `json:"reversal_of,omitempty"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type TransferRequest struct {
+	ID            string                `json:"id"`
+	FromAddress   string                `json:"from_address"`
+	ToAddress     string                `json:"to_address"`
+	Amount        int64                 `json:"amount"`
+	Status        TransferRequestStatus `json:"status"`
+	TransferID    *string               `json:"transfer_id,omitempty"`
+	SenderBalance *int64                `json:"sender_balance,omitempty"`
+	Error         *string               `json:"error,omitempty"`
+	CreatedAt     time.Time             `json:"created_at"`
+	ProcessedAt   *time.Time            `json:"processed_at,omitempty"`
+}
+
+type Wallet struct {
+	Address string `json:"address"`
+	Balance int64  `json:"balance"`
+}
+
+type WalletBalance struct {
+	Address string `json:"address"`
+	Balance int64  `json:"balance"`
+}
+
+type WalletDrift struct {
+	Address       string `json:"address"`
+	StoredBalance int64  `json:"stored_balance"`
+	LedgerBalance int64  `json:"ledger_balance"`
+	Drift         int64  `json:"drift"`
+}
+
+type WebhookAttempt struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  int32     `json:"status_code"`
+	Error       *string   `json:"error,omitempty"`
+	DurationMs  int32     `json:"duration_ms"`
+}
+
+type WebhookDelivery struct {
+	ID            string                `json:"id"`
+	EndpointID    string                `json:"endpoint_id"`
+	OutboxID      string                `json:"outbox_id"`
+	EventType     string                `json:"event_type"`
+	Status        WebhookDeliveryStatus `json:"status"`
+	Attempts      int32                 `json:"attempts"`
+	NextAttemptAt time.Time             `json:"next_attempt_at"`
+	LastError     *string               `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time            `json:"delivered_at,omitempty"`
+	AttemptLog    []*WebhookAttempt     `json:"attempt_log"`
+}
+
+type WebhookEndpoint struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WebhookRegistration struct {
+	Endpoint *WebhookEndpoint `json:"endpoint"`
+	Secret   string           `json:"secret"`
+}
+
+type TransferRequestStatus string
+
+const (
+	TransferRequestStatusPending   TransferRequestStatus = "PENDING"
+	TransferRequestStatusCompleted TransferRequestStatus = "COMPLETED"
+	TransferRequestStatusFailed    TransferRequestStatus = "FAILED"
+)
+
+var AllTransferRequestStatus = []TransferRequestStatus{
+	TransferRequestStatusPending,
+	TransferRequestStatusCompleted,
+	TransferRequestStatusFailed,
+}
+
+func (e TransferRequestStatus) IsValid() bool {
+	switch e {
+	case TransferRequestStatusPending, TransferRequestStatusCompleted, TransferRequestStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e TransferRequestStatus) String() string {
+	return string(e)
+}
+
+func (e *TransferRequestStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TransferRequestStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TransferRequestStatus", str)
+	}
+	return nil
+}
+
+func (e TransferRequestStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *TransferRequestStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e TransferRequestStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "DELIVERED"
+	WebhookDeliveryStatusDead      WebhookDeliveryStatus = "DEAD"
+)
+
+var AllWebhookDeliveryStatus = []WebhookDeliveryStatus{
+	WebhookDeliveryStatusPending,
+	WebhookDeliveryStatusDelivered,
+	WebhookDeliveryStatusDead,
+}
+
+func (e WebhookDeliveryStatus) IsValid() bool {
+	switch e {
+	case WebhookDeliveryStatusPending, WebhookDeliveryStatusDelivered, WebhookDeliveryStatusDead:
+		return true
+	}
+	return false
+}
+
+func (e WebhookDeliveryStatus) String() string {
+	return string(e)
+}
+
+func (e *WebhookDeliveryStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WebhookDeliveryStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WebhookDeliveryStatus", str)
+	}
+	return nil
+}
+
+func (e WebhookDeliveryStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *WebhookDeliveryStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e WebhookDeliveryStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dominika232323/token-transfer-api/graph/model"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+)
+
+func parseRequestID(id string) (int64, error) {
+	requestID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid transfer request id %q", id)
+	}
+
+	return requestID, nil
+}
+
+func toTransferRequest(request *db.TransferRequest) *model.TransferRequest {
+	result := &model.TransferRequest{
+		ID:            strconv.FormatInt(request.ID, 10),
+		FromAddress:   request.FromAddress,
+		ToAddress:     request.ToAddress,
+		Amount:        request.Amount,
+		Status:        model.TransferRequestStatus(strings.ToUpper(request.Status)),
+		CreatedAt:     request.CreatedAt,
+		ProcessedAt:   request.ProcessedAt,
+		SenderBalance: request.SenderBalance,
+	}
+
+	if request.TransferID != nil {
+		transferID := strconv.FormatInt(*request.TransferID, 10)
+		result.TransferID = &transferID
+	}
+
+	if request.Error != "" {
+		result.Error = &request.Error
+	}
+
+	return result
+}
+
+// convertUpdates forwards the updates of a watched request as models until
+// the updates end or ctx is cancelled.
+func convertUpdates(ctx context.Context, updates <-chan *db.TransferRequest) <-chan *model.TransferRequest {
+	converted := make(chan *model.TransferRequest, 1)
+
+	go func() {
+		defer close(converted)
+
+		for request := range updates {
+			select {
+			case <-ctx.Done():
+				return
+			case converted <- toTransferRequest(request):
+			}
+		}
+	}()
+
+	return converted
+}
@@ -0,0 +1,36 @@
+package graph
+
+import (
+	"strconv"
+
+	"github.com/dominika232323/token-transfer-api/graph/model"
+	"github.com/dominika232323/token-transfer-api/internal/reconcile"
+)
+
+func toReconciliationReport(report *reconcile.Report) *model.ReconciliationReport {
+	result := &model.ReconciliationReport{
+		GeneratedAt:       report.GeneratedAt,
+		WalletsChecked:    report.WalletsChecked,
+		WalletDrifts:      make([]*model.WalletDrift, 0, len(report.WalletDrifts)),
+		TotalSupply:       report.TotalSupply,
+		TotalBalances:     report.TotalBalances,
+		SupplyDrift:       report.SupplyDrift,
+		UnbalancedEntries: make([]string, 0, len(report.UnbalancedEntries)),
+		Reconciled:        report.Reconciled,
+	}
+
+	for _, drift := range report.WalletDrifts {
+		result.WalletDrifts = append(result.WalletDrifts, &model.WalletDrift{
+			Address:       drift.Address,
+			StoredBalance: drift.StoredBalance,
+			LedgerBalance: drift.LedgerBalance,
+			Drift:         drift.Drift,
+		})
+	}
+
+	for _, entryID := range report.UnbalancedEntries {
+		result.UnbalancedEntries = append(result.UnbalancedEntries, strconv.FormatInt(entryID, 10))
+	}
+
+	return result
+}
@@ -1,7 +1,11 @@
 package graph
 
-import "gorm.io/gorm"
+import (
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+	"gorm.io/gorm"
+)
 
 type Resolver struct {
-	DB *gorm.DB
+	DB        *gorm.DB
+	Transfers transfer.Service
 }
@@ -6,79 +6,254 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sort"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/dominika232323/token-transfer-api/internal/db"
-	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
+	"github.com/dominika232323/token-transfer-api/graph/model"
+	"github.com/dominika232323/token-transfer-api/internal/audit"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"github.com/dominika232323/token-transfer-api/internal/queue"
+	"github.com/dominika232323/token-transfer-api/internal/reconcile"
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+	"github.com/dominika232323/token-transfer-api/internal/webhook"
 )
 
 // Transfer is the resolver for the transfer field.
-func (r *mutationResolver) Transfer(ctx context.Context, fromAddress string, toAddress string, amount int32) (int32, error) {
-	database := r.Resolver.DB
-	var updatedBalance int32
+func (r *mutationResolver) Transfer(ctx context.Context, fromAddress string, toAddress string, amount int32) (int64, error) {
+	result, err := r.Resolver.Transfers.Transfer(ctx, fromAddress, toAddress, int64(amount))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.SenderBalance, nil
+}
+
+// SubmitTransfer is the resolver for the submitTransfer field.
+func (r *mutationResolver) SubmitTransfer(ctx context.Context, fromAddress string, toAddress string, amount int32) (*model.TransferRequest, error) {
+	request, err := queue.Submit(r.Resolver.DB.WithContext(ctx), fromAddress, toAddress, int64(amount))
+	if err != nil {
+		return nil, err
+	}
+
+	return toTransferRequest(request), nil
+}
+
+// ReverseTransfer is the resolver for the reverseTransfer field.
+func (r *mutationResolver) ReverseTransfer(ctx context.Context, id string, reason string, allowNegativeBalance *bool) (*model.Transfer, error) {
+	transferID, err := parseTransferID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	allowNegative := allowNegativeBalance != nil && *allowNegativeBalance
+
+	reversal, err := r.Resolver.Transfers.Reverse(ctx, transferID, reason, allowNegative)
+	if err != nil {
+		return nil, err
+	}
+
+	return toTransferModel(reversal), nil
+}
+
+// RegisterWebhook is the resolver for the registerWebhook field.
+func (r *mutationResolver) RegisterWebhook(ctx context.Context, url string) (*model.WebhookRegistration, error) {
+	endpoint, err := webhook.Register(r.Resolver.DB.WithContext(ctx), url)
+	if err != nil {
+		return nil, err
+	}
+
+	return toWebhookRegistration(endpoint), nil
+}
 
-	if amount < 0 {
-		return 0, fmt.Errorf("amount cannot be negative")
+// RotateWebhookSecret is the resolver for the rotateWebhookSecret field.
+func (r *mutationResolver) RotateWebhookSecret(ctx context.Context, id string) (*model.WebhookRegistration, error) {
+	endpointID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook endpoint id %q", id)
+	}
+
+	endpoint, err := webhook.RotateSecret(r.Resolver.DB.WithContext(ctx), endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toWebhookRegistration(endpoint), nil
+}
+
+// Wallet is the resolver for the wallet field.
+func (r *queryResolver) Wallet(ctx context.Context, address string) (*model.Wallet, error) {
+	wallet, err := r.Resolver.Transfers.GetWallet(ctx, address)
+	if errors.Is(err, transfer.ErrWalletNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
-	err := database.Transaction(func(tx *gorm.DB) error {
-		addresses := []string{fromAddress, toAddress}
-		sort.Strings(addresses)
+	return &model.Wallet{Address: wallet.Address, Balance: wallet.Balance}, nil
+}
 
-		var wallets [2]db.Wallet
+// Transfers is the resolver for the transfers field.
+func (r *queryResolver) Transfers(ctx context.Context, address string, limit *int32, before *string) ([]*model.Transfer, error) {
+	beforeID, err := parseOptionalID("before", before)
+	if err != nil {
+		return nil, err
+	}
 
-		for i, addr := range addresses {
-			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-				FirstOrCreate(&wallets[i], db.Wallet{Address: addr}).Error; err != nil {
-				return fmt.Errorf("failed to lock wallet %s: %w", addr, err)
-			}
-		}
+	count := transfer.DefaultHistoryLimit
+	if limit != nil {
+		count = int(*limit)
+	}
 
-		var sender, recipient *db.Wallet
+	transfers, err := r.Resolver.Transfers.History(ctx, address, count, beforeID)
+	if err != nil {
+		return nil, err
+	}
 
-		if wallets[0].Address == fromAddress {
-			sender = &wallets[0]
-			recipient = &wallets[1]
-		} else {
-			sender = &wallets[1]
-			recipient = &wallets[0]
-		}
+	result := make([]*model.Transfer, 0, len(transfers))
+	for i := range transfers {
+		result = append(result, toTransferModel(&transfers[i]))
+	}
 
-		if sender.Balance < int64(amount) {
-			return fmt.Errorf("Insufficient balance")
-		}
+	return result, nil
+}
 
-		if fromAddress == toAddress {
-			updatedBalance = int32(sender.Balance)
-			return nil
-		}
+// VerifyAuditChain is the resolver for the verifyAuditChain field.
+func (r *queryResolver) VerifyAuditChain(ctx context.Context, from *string, to *string) (*model.AuditChainReport, error) {
+	fromID, err := parseOptionalID("from", from)
+	if err != nil {
+		return nil, err
+	}
 
-		sender.Balance -= int64(amount)
+	toID, err := parseOptionalID("to", to)
+	if err != nil {
+		return nil, err
+	}
 
-		if err := tx.Save(sender).Error; err != nil {
-			return fmt.Errorf("failed to update sender balance: %v", err)
-		}
+	report, err := audit.Verify(r.Resolver.DB.WithContext(ctx), fromID, toID)
+	if err != nil {
+		return nil, err
+	}
 
-		recipient.Balance += int64(amount)
+	return toAuditChainReport(report), nil
+}
 
-		if err := tx.Save(recipient).Error; err != nil {
-			return fmt.Errorf("failed to update recipient balance: %v", err)
-		}
+// ReconciliationReport is the resolver for the reconciliationReport field.
+func (r *queryResolver) ReconciliationReport(ctx context.Context) (*model.ReconciliationReport, error) {
+	report, err := reconcile.Run(r.Resolver.DB.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
 
-		updatedBalance = int32(sender.Balance)
-		return nil
-	})
+	return toReconciliationReport(report), nil
+}
 
+// BalanceAt is the resolver for the balanceAt field.
+func (r *queryResolver) BalanceAt(ctx context.Context, address string, timestamp time.Time) (int64, error) {
+	balance, err := ledger.BalanceAt(r.Resolver.DB.WithContext(ctx), address, timestamp)
 	if err != nil {
 		return 0, err
 	}
 
-	return updatedBalance, nil
+	return balance, nil
+}
+
+// WalletsSnapshot is the resolver for the walletsSnapshot field.
+func (r *queryResolver) WalletsSnapshot(ctx context.Context, at time.Time) ([]*model.WalletBalance, error) {
+	balances, err := ledger.SnapshotAt(r.Resolver.DB.WithContext(ctx), at)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.WalletBalance, 0, len(balances))
+	for _, balance := range balances {
+		result = append(result, &model.WalletBalance{Address: balance.Address, Balance: balance.Balance})
+	}
+
+	return result, nil
+}
+
+// WebhookDeliveries is the resolver for the webhookDeliveries field.
+func (r *queryResolver) WebhookDeliveries(ctx context.Context, endpointID *string, status *model.WebhookDeliveryStatus, limit *int32) ([]*model.WebhookDelivery, error) {
+	endpoint, err := parseOptionalID("endpoint", endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	statusFilter := ""
+	if status != nil {
+		statusFilter = strings.ToLower(string(*status))
+	}
+
+	count := 50
+	if limit != nil {
+		count = int(*limit)
+	}
+
+	if count <= 0 || count > maxWebhookDeliveriesLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxWebhookDeliveriesLimit)
+	}
+
+	deliveries, err := webhook.Deliveries(r.Resolver.DB.WithContext(ctx), endpoint, statusFilter, count)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.WebhookDelivery, 0, len(deliveries))
+	for i := range deliveries {
+		result = append(result, toWebhookDelivery(&deliveries[i]))
+	}
+
+	return result, nil
+}
+
+// TransferStatus is the resolver for the transferStatus field.
+func (r *queryResolver) TransferStatus(ctx context.Context, id string) (*model.TransferRequest, error) {
+	requestID, err := parseRequestID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := queue.Find(r.Resolver.DB.WithContext(ctx), requestID)
+	if errors.Is(err, queue.ErrRequestNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toTransferRequest(request), nil
+}
+
+// TransferStatus is the resolver for the transferStatus field.
+func (r *subscriptionResolver) TransferStatus(ctx context.Context, id string) (<-chan *model.TransferRequest, error) {
+	requestID, err := parseRequestID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates, err := queue.Watch(ctx, r.Resolver.DB, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertUpdates(ctx, updates), nil
 }
 
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
 type mutationResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
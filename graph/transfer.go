@@ -0,0 +1,36 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dominika232323/token-transfer-api/graph/model"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+)
+
+func parseTransferID(id string) (int64, error) {
+	transferID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid transfer id %q", id)
+	}
+
+	return transferID, nil
+}
+
+func toTransferModel(transfer *db.Transfer) *model.Transfer {
+	result := &model.Transfer{
+		ID:          strconv.FormatInt(transfer.ID, 10),
+		FromAddress: transfer.FromAddress,
+		ToAddress:   transfer.ToAddress,
+		Amount:      transfer.Amount,
+		Reason:      transfer.Reason,
+		CreatedAt:   transfer.CreatedAt,
+	}
+
+	if transfer.ReversalOf != nil {
+		reversalOf := strconv.FormatInt(*transfer.ReversalOf, 10)
+		result.ReversalOf = &reversalOf
+	}
+
+	return result
+}
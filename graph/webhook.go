@@ -0,0 +1,60 @@
+package graph
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dominika232323/token-transfer-api/graph/model"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+)
+
+const maxWebhookDeliveriesLimit = 500
+
+func toWebhookRegistration(endpoint *db.WebhookEndpoint) *model.WebhookRegistration {
+	return &model.WebhookRegistration{
+		Endpoint: &model.WebhookEndpoint{
+			ID:        strconv.FormatInt(endpoint.ID, 10),
+			URL:       endpoint.URL,
+			Active:    endpoint.Active,
+			CreatedAt: endpoint.CreatedAt,
+			UpdatedAt: endpoint.UpdatedAt,
+		},
+		Secret: endpoint.Secret,
+	}
+}
+
+func toWebhookDelivery(delivery *db.WebhookDelivery) *model.WebhookDelivery {
+	result := &model.WebhookDelivery{
+		ID:            strconv.FormatInt(delivery.ID, 10),
+		EndpointID:    strconv.FormatInt(delivery.EndpointID, 10),
+		OutboxID:      strconv.FormatInt(delivery.OutboxID, 10),
+		EventType:     delivery.EventType,
+		Status:        model.WebhookDeliveryStatus(strings.ToUpper(delivery.Status)),
+		Attempts:      int32(delivery.Attempts),
+		NextAttemptAt: delivery.NextAttemptAt,
+		DeliveredAt:   delivery.DeliveredAt,
+		AttemptLog:    make([]*model.WebhookAttempt, 0, len(delivery.AttemptLog)),
+	}
+
+	if delivery.LastError != "" {
+		result.LastError = &delivery.LastError
+	}
+
+	for i := range delivery.AttemptLog {
+		attempt := &delivery.AttemptLog[i]
+
+		converted := &model.WebhookAttempt{
+			AttemptedAt: attempt.AttemptedAt,
+			StatusCode:  int32(attempt.StatusCode),
+			DurationMs:  int32(attempt.DurationMs),
+		}
+
+		if attempt.Error != "" {
+			converted.Error = &attempt.Error
+		}
+
+		result.AttemptLog = append(result.AttemptLog, converted)
+	}
+
+	return result
+}
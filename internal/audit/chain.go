@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/metrics"
+	"gorm.io/gorm"
+)
+
+// chainLockKey is the Postgres advisory lock that serializes appends so every
+// record chains to exactly one predecessor.
+const chainLockKey = 727001
+
+const verifyBatchSize = 500
+
+const (
+	OperationTransfer = "transfer"
+	OperationReversal = "reversal"
+	OperationIssuance = "issuance"
+)
+
+type Report struct {
+	Valid         bool   `json:"valid"`
+	Checked       int64  `json:"checked"`
+	FirstBrokenID *int64 `json:"first_broken_id,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// Append chains record to the latest audit record and inserts it. It must be
+// called inside the transaction that performs the balance change.
+func Append(tx *gorm.DB, record *db.AuditRecord) error {
+	if err := Lock(tx); err != nil {
+		return err
+	}
+
+	var last db.AuditRecord
+	err := tx.Order("id DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		return fmt.Errorf("failed to read audit log head: %w", err)
+	}
+
+	// Postgres keeps microseconds, so the hash must not depend on anything finer.
+	record.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	record.PrevHash = last.Hash
+	record.Hash = Hash(record)
+
+	if err := tx.Create(record).Error; err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+
+	return nil
+}
+
+// Lock takes the lock that orders audit records until the transaction ends.
+// Append takes it as well; taking it earlier lets the caller read balances
+// that are consistent with the order of the chain.
+func Lock(tx *gorm.DB) error {
+	// SQLite transactions already hold the database write lock.
+	if tx.Dialector.Name() == db.DriverSQLite {
+		return nil
+	}
+
+	started := time.Now()
+
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", chainLockKey).Error; err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+
+	metrics.LockWait.WithLabelValues("audit").Observe(time.Since(started).Seconds())
+	return nil
+}
+
+// Hash returns the hex encoded SHA-256 of the record contents and the hash of
+// its predecessor.
+func Hash(record *db.AuditRecord) string {
+	transferID := ""
+	if record.TransferID != nil {
+		transferID = strconv.FormatInt(*record.TransferID, 10)
+	}
+
+	fields := []string{
+		record.PrevHash,
+		record.Operation,
+		transferID,
+		record.FromAddress,
+		record.ToAddress,
+		strconv.FormatInt(record.Amount, 10),
+		strconv.FormatInt(record.FromBalance, 10),
+		strconv.FormatInt(record.ToBalance, 10),
+		record.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(fields, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify walks the audit records with ids in [from, to] and reports the first
+// record whose hash or link to its predecessor does not match. Zero bounds
+// mean the start and the end of the chain.
+func Verify(database *gorm.DB, from int64, to int64) (*Report, error) {
+	report := &Report{Valid: true}
+
+	var previous db.AuditRecord
+	if from > 0 {
+		err := database.Where("id < ?", from).Order("id DESC").Limit(1).Find(&previous).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit record before %d: %w", from, err)
+		}
+	}
+
+	expectedPrevHash := previous.Hash
+
+	query := database.Model(&db.AuditRecord{}).Where("id >= ?", from)
+	if to > 0 {
+		query = query.Where("id <= ?", to)
+	}
+
+	var records []db.AuditRecord
+	errBroken := errors.New("audit chain broken")
+
+	err := query.FindInBatches(&records, verifyBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range records {
+			record := &records[i]
+
+			switch {
+			case record.PrevHash != expectedPrevHash:
+				report.Reason = "previous hash does not match the preceding record"
+			case record.Hash != Hash(record):
+				report.Reason = "record hash does not match its contents"
+			default:
+				expectedPrevHash = record.Hash
+				report.Checked++
+				continue
+			}
+
+			report.Valid = false
+			report.FirstBrokenID = &record.ID
+			return errBroken
+		}
+
+		return nil
+	}).Error
+
+	if err != nil && !errors.Is(err, errBroken) {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+type adminKey struct{}
+
+// AdminMiddleware marks requests carrying "Authorization: Bearer <token>"
+// with the configured admin token as admin requests. Requests carrying an
+// Authorization header of another scheme are refused with 401. An empty
+// token disables admin access entirely.
+func AdminMiddleware(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		provided, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Authorization must use the Bearer scheme", http.StatusUnauthorized)
+			return
+		}
+
+		if token != "" {
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1 {
+				r = r.WithContext(WithAdmin(r.Context()))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
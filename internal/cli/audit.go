@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/dominika232323/token-transfer-api/internal/audit"
+)
+
+func verifyAuditChain(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("verify-audit-chain", flag.ContinueOnError)
+	from := flags.Int64("from", 0, "first audit record id to verify (default: start of the chain)")
+	to := flags.Int64("to", 0, "last audit record id to verify (default: end of the chain)")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	database, err := connect()
+	if err != nil {
+		return err
+	}
+
+	report, err := audit.Verify(database, *from, *to)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if err := json.NewEncoder(stdout).Encode(report); err != nil {
+			return err
+		}
+	} else if report.Valid {
+		fmt.Fprintf(stdout, "audit chain OK: %d records verified\n", report.Checked)
+	} else {
+		fmt.Fprintf(stdout, "audit chain broken at record %d after %d valid records: %s\n",
+			*report.FirstBrokenID, report.Checked, report.Reason)
+	}
+
+	if !report.Valid {
+		return fmt.Errorf("audit chain verification failed")
+	}
+
+	return nil
+}
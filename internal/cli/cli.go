@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dominika232323/token-transfer-api/internal/config"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/migrate"
+	"gorm.io/gorm"
+)
+
+type command struct {
+	usage string
+	run   func(args []string, stdout io.Writer) error
+}
+
+var commands = map[string]command{
+	"checkpoint": {
+		usage: "checkpoint [-at time]",
+		run:   takeCheckpoint,
+	},
+	"config": {
+		usage: "config print",
+		run:   printConfig,
+	},
+	"check-ledger": {
+		usage: "check-ledger [-rebuild] [-json]",
+		run:   checkLedger,
+	},
+	"migrate": {
+		usage: "migrate up|down [-steps n]|status",
+		run:   runMigrations,
+	},
+	"reconcile": {
+		usage: "reconcile [-json]",
+		run:   runReconciliation,
+	},
+	"replay": {
+		usage: "replay [-json]",
+		run:   replayEvents,
+	},
+	"statement": {
+		usage: "statement -address addr -from time -to time [-format csv|jsonl|text] [-o file]",
+		run:   exportStatement,
+	},
+	"verify-audit-chain": {
+		usage: "verify-audit-chain [-from id] [-to id] [-json]",
+		run:   verifyAuditChain,
+	},
+}
+
+// Run executes the subcommand named by args[0] with the remaining arguments.
+func Run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", usage())
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage())
+	}
+
+	return cmd.run(args[1:], os.Stdout)
+}
+
+func usage() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("available commands:\n")
+
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s\n", commands[name].usage)
+	}
+
+	return b.String()
+}
+
+// connect opens the database and refuses to use it until it is migrated.
+func connect() (*gorm.DB, error) {
+	database, err := openDatabase()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrate.Check(database); err != nil {
+		return nil, err
+	}
+
+	return database, nil
+}
+
+func openDatabase() (*gorm.DB, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Connect(context.Background(), cfg.Database)
+}
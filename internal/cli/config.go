@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dominika232323/token-transfer-api/internal/config"
+)
+
+func printConfig(args []string, stdout io.Writer) error {
+	if len(args) != 1 || args[0] != "print" {
+		return fmt.Errorf("usage: config print")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	content, err := cfg.Redacted().YAML()
+	if err != nil {
+		return err
+	}
+
+	_, err = stdout.Write(content)
+	return err
+}
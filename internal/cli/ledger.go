@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+)
+
+func checkLedger(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("check-ledger", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	rebuild := flags.Bool("rebuild", false, "overwrite stored balances with balances recomputed from the journal")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	database, err := connect()
+	if err != nil {
+		return err
+	}
+
+	if *rebuild {
+		updated, err := ledger.Rebuild(database)
+		if err != nil {
+			return err
+		}
+
+		if !*asJSON {
+			fmt.Fprintf(stdout, "rebuilt %d wallet balances from the journal\n", updated)
+		}
+	}
+
+	result, err := ledger.Check(database)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if err := json.NewEncoder(stdout).Encode(result); err != nil {
+			return err
+		}
+	} else {
+		for _, mismatch := range result.Mismatches {
+			fmt.Fprintf(stdout, "wallet %s: stored balance %d, journal balance %d\n",
+				mismatch.Address, mismatch.Stored, mismatch.Computed)
+		}
+
+		for _, entryID := range result.UnbalancedEntries {
+			fmt.Fprintf(stdout, "journal entry %d does not sum to zero\n", entryID)
+		}
+
+		if result.Consistent {
+			fmt.Fprintln(stdout, "ledger consistent: all balances match the journal")
+		}
+	}
+
+	if !result.Consistent {
+		return fmt.Errorf("ledger consistency check failed")
+	}
+
+	return nil
+}
+
+func takeCheckpoint(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("checkpoint", flag.ContinueOnError)
+	at := flags.String("at", "", "RFC 3339 time to checkpoint balances at (default: now minus the checkpoint lag)")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	asOf := time.Now().Add(-ledger.CheckpointLag)
+
+	if *at != "" {
+		parsed, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return fmt.Errorf("invalid -at: %w", err)
+		}
+		asOf = parsed
+	}
+
+	database, err := connect()
+	if err != nil {
+		return err
+	}
+
+	checkpoint, err := ledger.TakeCheckpoint(database, asOf)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "checkpoint %d taken at %s\n", checkpoint.ID, checkpoint.TakenAt.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/migrate"
+)
+
+func runMigrations(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate action, expected up, down or status")
+	}
+
+	action := args[0]
+
+	flags := flag.NewFlagSet("migrate "+action, flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "number of migrations to revert")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch action {
+	case "up":
+		database, err := openDatabase()
+		if err != nil {
+			return err
+		}
+
+		applied, err := migrate.Up(database)
+		for _, migration := range applied {
+			fmt.Fprintf(stdout, "applied %04d_%s\n", migration.Version, migration.Name)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if len(applied) == 0 {
+			fmt.Fprintln(stdout, "schema is up to date")
+		}
+
+		seeded, err := migrate.Seed(database)
+		if err != nil {
+			return err
+		}
+
+		if seeded {
+			fmt.Fprintf(stdout, "issued %d tokens to %s\n", migrate.GenesisSupply, migrate.GenesisAddress)
+		}
+
+		return nil
+
+	case "down":
+		if *steps <= 0 {
+			return fmt.Errorf("-steps must be positive")
+		}
+
+		database, err := openDatabase()
+		if err != nil {
+			return err
+		}
+
+		reverted, err := migrate.Down(database, *steps)
+		for _, migration := range reverted {
+			fmt.Fprintf(stdout, "reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+
+		return err
+
+	case "status":
+		database, err := openDatabase()
+		if err != nil {
+			return err
+		}
+
+		statuses, err := migrate.List(database)
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+
+			fmt.Fprintf(stdout, "%04d_%s\t%s\n", status.Version, status.Name, applied)
+		}
+
+		return nil
+
+	default:
+		return fmt.Errorf("unknown migrate action %q, expected up, down or status", action)
+	}
+}
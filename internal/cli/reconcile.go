@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/dominika232323/token-transfer-api/internal/reconcile"
+)
+
+func runReconciliation(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the drift report as JSON")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	database, err := connect()
+	if err != nil {
+		return err
+	}
+
+	report, err := reconcile.Run(database)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		err = report.WriteJSON(stdout)
+	} else {
+		err = report.WriteText(stdout)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !report.Reconciled {
+		return fmt.Errorf("reconciliation found drift")
+	}
+
+	return nil
+}
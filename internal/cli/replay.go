@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/dominika232323/token-transfer-api/internal/eventstore"
+)
+
+func replayEvents(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	rebuild := flags.Bool("rebuild", false, "overwrite the live balances and transfers with the projections replayed from the events")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	database, err := connect()
+	if err != nil {
+		return err
+	}
+
+	if *rebuild {
+		rebuilt, err := eventstore.Rebuild(database)
+		if err != nil {
+			return err
+		}
+
+		if !*asJSON {
+			fmt.Fprintf(stdout, "rebuilt %d wallet balances and transfers from the events\n", rebuilt)
+		}
+	}
+
+	result, err := eventstore.Replay(database)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if err := json.NewEncoder(stdout).Encode(result); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(stdout, "replayed %d events\n", result.EventsReplayed)
+
+		if result.SnapshotTransferID > 0 {
+			fmt.Fprintf(stdout, "transfers up to %d predate the event store and were not compared\n", result.SnapshotTransferID)
+		}
+
+		for _, mismatch := range result.BalanceMismatches {
+			fmt.Fprintf(stdout, "wallet %s: live balance %d, replayed balance %d\n",
+				mismatch.Address, mismatch.LiveBalance, mismatch.ReplayBalance)
+		}
+
+		for _, transferID := range result.MismatchedTransfers {
+			fmt.Fprintf(stdout, "transfer %d differs between the live table and the replay\n", transferID)
+		}
+
+		if result.Match {
+			fmt.Fprintln(stdout, "projections match the live tables")
+		}
+	}
+
+	if !result.Match {
+		return fmt.Errorf("replayed projections do not match the live tables")
+	}
+
+	return nil
+}
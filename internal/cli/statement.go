@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/dominika232323/token-transfer-api/internal/statement"
+)
+
+func exportStatement(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("statement", flag.ContinueOnError)
+	address := flags.String("address", "", "wallet address")
+	from := flags.String("from", "", "start of the range, RFC 3339 time or YYYY-MM-DD (exclusive)")
+	to := flags.String("to", "", "end of the range, RFC 3339 time or YYYY-MM-DD (inclusive)")
+	formatName := flags.String("format", "csv", "output format: csv, jsonl or text")
+	output := flags.String("o", "", "write the statement to this file instead of stdout")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *address == "" {
+		return fmt.Errorf("-address is required")
+	}
+
+	format, err := statement.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	fromTime, err := statement.ParseTime(*from)
+	if err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+
+	toTime, err := statement.ParseTime(*to)
+	if err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+
+	// Connect first, so that a database that cannot be reached leaves no file
+	// behind.
+	database, err := connect()
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return statement.Write(database, stdout, *address, fromTime, toTime, format)
+	}
+
+	return writeFile(*output, func(out io.Writer) error {
+		return statement.Write(database, out, *address, fromTime, toTime, format)
+	})
+}
+
+// writeFile writes to a temporary file next to path and renames it to path
+// once write succeeds, so that path never holds a partial file. The temporary
+// file is removed if anything fails.
+func writeFile(path string, write func(out io.Writer) error) (err error) {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	if err := write(file); err != nil {
+		return err
+	}
+
+	if err := file.Chmod(0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
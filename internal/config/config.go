@@ -0,0 +1,609 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "REDACTED"
+
+// Config is the complete application configuration. Every field can be set
+// in the YAML file named by CONFIG_FILE and overridden by the environment
+// variable in its env tag.
+type Config struct {
+	Port       int      `yaml:"port" env:"PORT"`
+	AdminToken string   `yaml:"admin_token" env:"ADMIN_TOKEN" secret:"true"`
+	Server     Server   `yaml:"server"`
+	Database   Database `yaml:"database"`
+	Features   Features `yaml:"features"`
+	Tracing    Tracing  `yaml:"tracing"`
+	Log        Log      `yaml:"log"`
+}
+
+type Server struct {
+	// ShutdownTimeout bounds how long a shutdown waits for requests and
+	// workers in flight before the database pool is closed regardless.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+
+	// The timeouts of http.Server of the same names. 0 disables them.
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+
+	// MaxBodyBytes is the largest request body /query accepts.
+	MaxBodyBytes int `yaml:"max_body_bytes" env:"HTTP_MAX_BODY_BYTES"`
+
+	// CORSOrigins are the origins, such as https://wallet.example.com, whose
+	// pages may call the API from a browser. "*" allows every origin.
+	CORSOrigins []string `yaml:"cors_origins" env:"CORS_ALLOWED_ORIGINS"`
+}
+
+type Database struct {
+	// Driver is "postgres" or "sqlite".
+	Driver string `yaml:"driver" env:"DB_DRIVER"`
+
+	// DSN replaces the Postgres connection settings below when set.
+	DSN string `yaml:"dsn" env:"DATABASE_URL" secret:"true"`
+
+	Host     string `yaml:"host" env:"POSTGRES_HOST"`
+	Port     int    `yaml:"port" env:"POSTGRES_PORT"`
+	User     string `yaml:"user" env:"POSTGRES_USER"`
+	Password string `yaml:"password" env:"POSTGRES_PASSWORD" secret:"true"`
+	Name     string `yaml:"name" env:"POSTGRES_DB"`
+
+	// SSLMode is a libpq sslmode: disable, allow, prefer, require, verify-ca
+	// or verify-full.
+	SSLMode     string `yaml:"sslmode" env:"POSTGRES_SSLMODE"`
+	SSLRootCert string `yaml:"sslrootcert" env:"POSTGRES_SSLROOTCERT"`
+	SSLCert     string `yaml:"sslcert" env:"POSTGRES_SSLCERT"`
+	SSLKey      string `yaml:"sslkey" env:"POSTGRES_SSLKEY"`
+
+	SQLitePath string `yaml:"sqlite_path" env:"SQLITE_PATH"`
+
+	// MaxOpenConns of 0 means no limit.
+	MaxOpenConns int `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns int `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+
+	// ConnMaxLifetime and ConnMaxIdleTime close pooled connections after
+	// this long, so connections to a restarted server are replaced. 0 keeps
+	// them forever.
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+
+	// StartupTimeout is how long to keep retrying the first connection
+	// before giving up.
+	StartupTimeout time.Duration `yaml:"startup_timeout" env:"DB_STARTUP_TIMEOUT"`
+
+	// Locking is how transfers guard wallets against concurrent changes:
+	// "pessimistic" locks them with SELECT ... FOR UPDATE, "optimistic"
+	// updates them only if their version has not changed since they were
+	// read.
+	Locking string `yaml:"locking" env:"DB_LOCKING"`
+
+	// Isolation is the isolation level of transfer transactions:
+	// read-committed, repeatable-read or serializable. SQLite transactions
+	// are always serializable.
+	Isolation string `yaml:"isolation" env:"DB_ISOLATION"`
+
+	// TxMaxRetries is how many times a transaction that failed with a
+	// serialization failure or a deadlock is run again.
+	TxMaxRetries int `yaml:"tx_max_retries" env:"DB_TX_MAX_RETRIES"`
+
+	// HealthInterval is how often the pool is pinged and its stats logged.
+	// 0 disables the health monitor.
+	HealthInterval time.Duration `yaml:"health_interval" env:"DB_HEALTH_INTERVAL"`
+
+	// SlowQueryThreshold is the duration above which a statement is logged
+	// as slow, at warn level. 0 disables slow query warnings.
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" env:"DB_SLOW_QUERY_THRESHOLD"`
+
+	// StatementTimeout aborts statements running longer than this on
+	// Postgres. 0 disables it.
+	StatementTimeout time.Duration `yaml:"statement_timeout" env:"DB_STATEMENT_TIMEOUT"`
+}
+
+type Features struct {
+	EventSourcing      bool          `yaml:"event_sourcing" env:"EVENT_SOURCING"`
+	RESTAPI            bool          `yaml:"rest_api" env:"REST_API"`
+	Playground         bool          `yaml:"playground" env:"PLAYGROUND"`
+	Metrics            bool          `yaml:"metrics" env:"METRICS"`
+	WebhookDispatcher  bool          `yaml:"webhook_dispatcher" env:"WEBHOOK_DISPATCHER"`
+	ReconcileInterval  time.Duration `yaml:"reconcile_interval" env:"RECONCILE_INTERVAL"`
+	CheckpointInterval time.Duration `yaml:"checkpoint_interval" env:"CHECKPOINT_INTERVAL"`
+
+	// HotWallets keep their balance in HotWalletShards sub-balances, so that
+	// transfers from them do not wait for each other. The environment
+	// variable is a comma separated list.
+	HotWallets      []string `yaml:"hot_wallets" env:"HOT_WALLETS"`
+	HotWalletShards int      `yaml:"hot_wallet_shards" env:"HOT_WALLET_SHARDS"`
+
+	// QueueWorkers process transfers submitted with submitTransfer, taking
+	// up to QueueBatchSize requests of one sender at a time. 0 leaves the
+	// queue to other instances.
+	QueueWorkers      int           `yaml:"queue_workers" env:"QUEUE_WORKERS"`
+	QueueBatchSize    int           `yaml:"queue_batch_size" env:"QUEUE_BATCH_SIZE"`
+	QueuePollInterval time.Duration `yaml:"queue_poll_interval" env:"QUEUE_POLL_INTERVAL"`
+}
+
+type Tracing struct {
+	// Exporter is where spans are sent: "none", "stdout", "file" or "otlp".
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER"`
+
+	// File is the file the file exporter appends spans to, one JSON object
+	// per line.
+	File string `yaml:"file" env:"TRACING_FILE"`
+
+	// Endpoint is the URL of the OTLP/HTTP collector. When empty the
+	// standard OTEL_EXPORTER_OTLP_* variables apply.
+	Endpoint string `yaml:"endpoint" env:"TRACING_OTLP_ENDPOINT"`
+
+	// SampleRatio is the fraction of traces started here that are recorded.
+	// Traces started by a caller follow the caller's decision.
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+}
+
+type Log struct {
+	// Level is debug, info, warn or error. Every SQL statement is logged at
+	// debug level.
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+
+	// Format is json or text.
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+}
+
+func Default() *Config {
+	return &Config{
+		Port: 8080,
+		Server: Server{
+			ShutdownTimeout:   30 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxBodyBytes:      1 << 20,
+		},
+		Database: Database{
+			Driver:             "postgres",
+			Host:               "localhost",
+			Port:               5432,
+			SSLMode:            "disable",
+			SQLitePath:         "token-transfer.db",
+			MaxOpenConns:       25,
+			MaxIdleConns:       5,
+			ConnMaxLifetime:    30 * time.Minute,
+			ConnMaxIdleTime:    5 * time.Minute,
+			ConnectTimeout:     5 * time.Second,
+			StartupTimeout:     time.Minute,
+			Locking:            "pessimistic",
+			Isolation:          "read-committed",
+			TxMaxRetries:       5,
+			HealthInterval:     30 * time.Second,
+			SlowQueryThreshold: 200 * time.Millisecond,
+		},
+		Features: Features{
+			RESTAPI:           true,
+			Playground:        true,
+			Metrics:           true,
+			WebhookDispatcher: true,
+			HotWalletShards:   16,
+			QueueWorkers:      4,
+			QueueBatchSize:    100,
+			QueuePollInterval: 100 * time.Millisecond,
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			File:        "traces.jsonl",
+			SampleRatio: 1,
+			ServiceName: "token-transfer-api",
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "json",
+		},
+	}
+}
+
+// Load reads .env, if present, into the environment and then returns
+// FromEnv(CONFIG_FILE).
+func Load() (*Config, error) {
+	_ = godotenv.Load(".env")
+	return FromEnv(os.Getenv("CONFIG_FILE"))
+}
+
+// FromEnv applies the YAML file at yamlPath, if not empty, and then the
+// environment on top of the defaults, and validates the result.
+func FromEnv(yamlPath string) (*Config, error) {
+	cfg := Default()
+
+	if yamlPath != "" {
+		content, err := os.ReadFile(yamlPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("invalid config file %s: %w", yamlPath, err)
+		}
+	}
+
+	problems := applyEnv(reflect.ValueOf(cfg).Elem())
+	problems = append(problems, cfg.validate()...)
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) validate() []string {
+	var problems []string
+
+	if c.Port < 1 || c.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("PORT must be between 1 and 65535, got %d", c.Port))
+	}
+
+	problems = append(problems, c.Server.validate()...)
+	problems = append(problems, c.Database.validate()...)
+
+	if c.Features.ReconcileInterval < 0 {
+		problems = append(problems, "RECONCILE_INTERVAL must not be negative")
+	}
+
+	if c.Features.CheckpointInterval < 0 {
+		problems = append(problems, "CHECKPOINT_INTERVAL must not be negative")
+	}
+
+	if len(c.Features.HotWallets) > 0 && c.Features.HotWalletShards < 1 {
+		problems = append(problems, "HOT_WALLET_SHARDS must be at least 1")
+	}
+
+	if c.Features.QueueWorkers < 0 {
+		problems = append(problems, "QUEUE_WORKERS must not be negative")
+	}
+
+	if c.Features.QueueBatchSize < 1 {
+		problems = append(problems, "QUEUE_BATCH_SIZE must be at least 1")
+	}
+
+	if c.Features.QueuePollInterval <= 0 {
+		problems = append(problems, "QUEUE_POLL_INTERVAL must be positive")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	case "file":
+		if c.Tracing.File == "" {
+			problems = append(problems, "TRACING_FILE is required with the file exporter")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("TRACING_EXPORTER must be none, stdout, file or otlp, got %q", c.Tracing.Exporter))
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL must be debug, info, warn or error, got %q", c.Log.Level))
+	}
+
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		problems = append(problems, fmt.Sprintf("LOG_FORMAT must be json or text, got %q", c.Log.Format))
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	return problems
+}
+
+func (s *Server) validate() []string {
+	var problems []string
+
+	if s.ShutdownTimeout <= 0 {
+		problems = append(problems, "SHUTDOWN_TIMEOUT must be positive")
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"HTTP_READ_HEADER_TIMEOUT", s.ReadHeaderTimeout},
+		{"HTTP_READ_TIMEOUT", s.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", s.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", s.IdleTimeout},
+	}
+
+	for _, timeout := range timeouts {
+		if timeout.value < 0 {
+			problems = append(problems, timeout.name+" must not be negative")
+		}
+	}
+
+	if s.MaxBodyBytes < 1 {
+		problems = append(problems, "HTTP_MAX_BODY_BYTES must be at least 1")
+	}
+
+	for _, origin := range s.CORSOrigins {
+		if origin == "*" {
+			continue
+		}
+
+		parsed, err := url.Parse(origin)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" ||
+			parsed.Path != "" || parsed.RawQuery != "" || parsed.User != nil {
+			problems = append(problems, fmt.Sprintf("CORS_ALLOWED_ORIGINS must hold origins like https://wallet.example.com, got %q", origin))
+		}
+	}
+
+	return problems
+}
+
+func (d *Database) validate() []string {
+	var problems []string
+
+	switch d.Driver {
+	case "postgres":
+		if d.DSN == "" {
+			if d.Host == "" {
+				problems = append(problems, "POSTGRES_HOST is required")
+			}
+
+			if d.User == "" {
+				problems = append(problems, "POSTGRES_USER is required")
+			}
+
+			if d.Name == "" {
+				problems = append(problems, "POSTGRES_DB is required")
+			}
+
+			if d.Port < 1 || d.Port > 65535 {
+				problems = append(problems, fmt.Sprintf("POSTGRES_PORT must be between 1 and 65535, got %d", d.Port))
+			}
+		}
+
+		switch d.SSLMode {
+		case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			problems = append(problems, fmt.Sprintf(
+				"POSTGRES_SSLMODE must be disable, allow, prefer, require, verify-ca or verify-full, got %q", d.SSLMode))
+		}
+
+		if (d.SSLCert == "") != (d.SSLKey == "") {
+			problems = append(problems, "POSTGRES_SSLCERT and POSTGRES_SSLKEY must be set together")
+		}
+	case "sqlite":
+		if d.SQLitePath == "" {
+			problems = append(problems, "SQLITE_PATH is required")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("DB_DRIVER must be postgres or sqlite, got %q", d.Driver))
+	}
+
+	if d.MaxOpenConns < 0 {
+		problems = append(problems, "DB_MAX_OPEN_CONNS must not be negative")
+	}
+
+	if d.MaxIdleConns < 0 {
+		problems = append(problems, "DB_MAX_IDLE_CONNS must not be negative")
+	}
+
+	if d.MaxOpenConns > 0 && d.MaxIdleConns > d.MaxOpenConns {
+		problems = append(problems, "DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS")
+	}
+
+	if d.ConnMaxLifetime < 0 {
+		problems = append(problems, "DB_CONN_MAX_LIFETIME must not be negative")
+	}
+
+	if d.ConnMaxIdleTime < 0 {
+		problems = append(problems, "DB_CONN_MAX_IDLE_TIME must not be negative")
+	}
+
+	if d.ConnectTimeout < 0 {
+		problems = append(problems, "DB_CONNECT_TIMEOUT must not be negative")
+	}
+
+	if d.StartupTimeout < 0 {
+		problems = append(problems, "DB_STARTUP_TIMEOUT must not be negative")
+	}
+
+	switch d.Locking {
+	case "pessimistic", "optimistic":
+	default:
+		problems = append(problems, fmt.Sprintf("DB_LOCKING must be pessimistic or optimistic, got %q", d.Locking))
+	}
+
+	switch d.Isolation {
+	case "read-committed", "repeatable-read", "serializable":
+	default:
+		problems = append(problems, fmt.Sprintf(
+			"DB_ISOLATION must be read-committed, repeatable-read or serializable, got %q", d.Isolation))
+	}
+
+	if d.TxMaxRetries < 0 {
+		problems = append(problems, "DB_TX_MAX_RETRIES must not be negative")
+	}
+
+	if d.HealthInterval < 0 {
+		problems = append(problems, "DB_HEALTH_INTERVAL must not be negative")
+	}
+
+	if d.SlowQueryThreshold < 0 {
+		problems = append(problems, "DB_SLOW_QUERY_THRESHOLD must not be negative")
+	}
+
+	if d.StatementTimeout < 0 {
+		problems = append(problems, "DB_STATEMENT_TIMEOUT must not be negative")
+	}
+
+	return problems
+}
+
+// PostgresDSN returns DSN if it is set, and otherwise builds a libpq
+// key/value connection string from the individual settings.
+func (d *Database) PostgresDSN() string {
+	if d.DSN != "" {
+		return d.DSN
+	}
+
+	type setting struct{ key, value string }
+
+	settings := []setting{
+		{"host", d.Host},
+		{"port", strconv.Itoa(d.Port)},
+		{"user", d.User},
+		{"password", d.Password},
+		{"dbname", d.Name},
+		{"sslmode", d.SSLMode},
+		{"sslrootcert", d.SSLRootCert},
+		{"sslcert", d.SSLCert},
+		{"sslkey", d.SSLKey},
+	}
+
+	if d.ConnectTimeout > 0 {
+		settings = append(settings, setting{"connect_timeout", seconds(d.ConnectTimeout)})
+	}
+
+	if d.StatementTimeout > 0 {
+		settings = append(settings, setting{"statement_timeout", strconv.FormatInt(d.StatementTimeout.Milliseconds(), 10)})
+	}
+
+	parts := make([]string, 0, len(settings))
+	for _, setting := range settings {
+		if setting.value != "" {
+			parts = append(parts, setting.key+"="+quote(setting.value))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Redacted returns a copy of c with every secret that is set replaced.
+func (c *Config) Redacted() *Config {
+	copied := *c
+	redact(reflect.ValueOf(&copied).Elem())
+	return &copied
+}
+
+// YAML returns c in the format of the config file.
+func (c *Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+func applyEnv(value reflect.Value) []string {
+	var problems []string
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		structField := value.Type().Field(i)
+
+		if field.Kind() == reflect.Struct {
+			problems = append(problems, applyEnv(field)...)
+			continue
+		}
+
+		name := structField.Tag.Get("env")
+		raw, ok := os.LookupEnv(name)
+		if name == "" || !ok || raw == "" {
+			continue
+		}
+
+		if err := set(field, raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	return problems
+}
+
+func set(field reflect.Value, raw string) error {
+	switch {
+	case field.Type() == reflect.TypeOf(time.Duration(0)):
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		field.SetInt(int64(duration))
+	case field.Kind() == reflect.Int:
+		number, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		field.SetInt(int64(number))
+	case field.Kind() == reflect.Float64:
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		field.SetFloat(number)
+	case field.Kind() == reflect.Bool:
+		flag, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		field.SetBool(flag)
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+	case field.Type() == reflect.TypeOf([]string(nil)):
+		values := []string{}
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+
+	return nil
+}
+
+func redact(value reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			redact(field)
+			continue
+		}
+
+		if value.Type().Field(i).Tag.Get("secret") == "true" && field.String() != "" {
+			field.SetString(redacted)
+		}
+	}
+}
+
+func seconds(duration time.Duration) string {
+	return strconv.FormatInt(int64((duration+time.Second-1)/time.Second), 10)
+}
+
+// quote quotes a libpq connection string value when it needs it.
+func quote(value string) string {
+	if !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	return "'" + escaped + "'"
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/config"
+	"github.com/dominika232323/token-transfer-api/internal/tracing"
+	"gorm.io/gorm"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+const (
+	initialRetryDelay = 500 * time.Millisecond
+	maxRetryDelay     = 10 * time.Second
+)
+
+// Connect opens the database selected by cfg.Driver and pings it. While the
+// database is unreachable it retries with exponential backoff until
+// cfg.StartupTimeout has passed or ctx is cancelled.
+func Connect(ctx context.Context, cfg config.Database) (*gorm.DB, error) {
+	deadline := time.Now().Add(cfg.StartupTimeout)
+	delay := initialRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		db, err := connect(ctx, cfg)
+		if err == nil {
+			slog.Info("Connected to database", slog.String("driver", cfg.Driver), slog.Int("attempts", attempt))
+			return db, nil
+		}
+
+		if time.Now().Add(delay).After(deadline) {
+			return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", attempt, err)
+		}
+
+		slog.Warn("Database is not ready",
+			slog.Int("attempt", attempt),
+			slog.String("error", err.Error()),
+			slog.Duration("retry_in", delay))
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to connect to database: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+func connect(ctx context.Context, cfg config.Database) (*gorm.DB, error) {
+	db, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get raw DB: %w", err)
+	}
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("database ping failed: %w", err)
+	}
+
+	return db, nil
+}
+
+// Open opens the database selected by cfg.Driver and configures its
+// connection pool and the retries of Transaction, without checking that it
+// is reachable.
+func Open(cfg config.Database) (*gorm.DB, error) {
+	var db *gorm.DB
+	var err error
+
+	switch cfg.Driver {
+	case DriverPostgres:
+		db, err = OpenPostgres(cfg.PostgresDSN())
+	case DriverSQLite:
+		db, err = OpenSQLite(cfg.SQLitePath)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get raw DB: %w", err)
+	}
+
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	policy := DefaultRetryPolicy
+	policy.MaxRetries = cfg.TxMaxRetries
+	SetRetryPolicy(policy)
+
+	db.Logger = NewLogger(cfg.SlowQueryThreshold)
+
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to install tracing: %w", err)
+	}
+
+	return db, nil
+}
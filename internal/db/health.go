@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StartHealthMonitor pings database every interval until ctx is cancelled.
+// It logs the connection pool stats on every check at debug level, and logs
+// when the database becomes unreachable and when it recovers.
+func StartHealthMonitor(ctx context.Context, database *gorm.DB, interval time.Duration) {
+	sqlDB, err := database.DB()
+	if err != nil {
+		slog.Error("Database health monitor not started", slog.String("error", err.Error()))
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		healthy := true
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pingCtx, cancel := context.WithTimeout(ctx, interval)
+				err := sqlDB.PingContext(pingCtx)
+				cancel()
+
+				switch {
+				case err != nil && healthy:
+					slog.Error("Database is unreachable", slog.String("error", err.Error()))
+				case err == nil && !healthy:
+					slog.Info("Database is reachable again")
+				}
+				healthy = err == nil
+
+				stats := sqlDB.Stats()
+				slog.Debug("Database pool",
+					slog.Int("open", stats.OpenConnections),
+					slog.Int("in_use", stats.InUse),
+					slog.Int("idle", stats.Idle),
+					slog.Int64("wait_count", stats.WaitCount),
+					slog.Duration("wait_duration", stats.WaitDuration))
+			}
+		}
+	}()
+}
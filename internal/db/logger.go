@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Logger writes GORM's log through slog. Every statement is logged at debug
+// level, statements slower than the slow query threshold at warn level and
+// failed statements at error level. Records carry the request and trace of
+// the statement's context. Statements are logged with their placeholders,
+// never with their parameter values, which include secrets such as webhook
+// signing keys.
+type Logger struct {
+	SlowThreshold time.Duration
+}
+
+var (
+	_ logger.Interface  = (*Logger)(nil)
+	_ gorm.ParamsFilter = (*Logger)(nil)
+)
+
+// explainedPlaceholder matches a numbered placeholder as GORM renders it when
+// it has no value to put in its place.
+var explainedPlaceholder = regexp.MustCompile(`\$(\d+)\$`)
+
+func NewLogger(slowThreshold time.Duration) *Logger {
+	return &Logger{SlowThreshold: slowThreshold}
+}
+
+// LogMode is a no-op; the level of the slog handler decides what is logged.
+func (l *Logger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (l *Logger) Info(ctx context.Context, message string, args ...any) {
+	slog.InfoContext(ctx, fmt.Sprintf(message, args...))
+}
+
+func (l *Logger) Warn(ctx context.Context, message string, args ...any) {
+	slog.WarnContext(ctx, fmt.Sprintf(message, args...))
+}
+
+func (l *Logger) Error(ctx context.Context, message string, args ...any) {
+	slog.ErrorContext(ctx, fmt.Sprintf(message, args...))
+}
+
+// ParamsFilter drops the parameter values of the statements GORM logs.
+func (l *Logger) ParamsFilter(ctx context.Context, sql string, params ...any) (string, []any) {
+	return sql, nil
+}
+
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+
+	level, message := slog.LevelDebug, "Query"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, message = slog.LevelError, "Query failed"
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold:
+		level, message = slog.LevelWarn, "Slow query"
+	}
+
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	sql = explainedPlaceholder.ReplaceAllString(sql, "$$$1")
+
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+	}
+
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	slog.LogAttrs(ctx, level, message, attrs...)
+}
@@ -1,7 +1,167 @@
 package db
 
+import "time"
+
+// The schema of these tables is defined by the migrations in
+// internal/migrate; tests/migrate_test.go checks that the two agree.
+
 type Wallet struct {
 	ID      int64  `gorm:"primaryKey;autoIncrement"`
 	Address string `gorm:"uniqueIndex;size:42;not null"`
+
+	// Balance is stored in wallets.balance and, for sharded wallets, in
+	// wallet_shards. Wallets loaded through ledger.FindWallet hold the sum.
+	Balance int64 `gorm:"not null"`
+
+	// Version is incremented on every change of wallets.balance.
+	Version int64 `gorm:"not null;default:0"`
+
+	// Shards is the number of wallet_shards rows of a hot wallet, and 0 for
+	// every other wallet.
+	Shards int `gorm:"not null;default:0"`
+}
+
+// WalletShard holds part of the balance of a hot wallet.
+type WalletShard struct {
+	Address string `gorm:"primaryKey;size:42"`
+	Shard   int    `gorm:"primaryKey;autoIncrement:false"`
+	Balance int64  `gorm:"not null;default:0"`
+}
+
+type Transfer struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement"`
+	FromAddress string    `gorm:"size:42;not null;index"`
+	ToAddress   string    `gorm:"size:42;not null;index"`
+	Amount      int64     `gorm:"not null"`
+	ReversalOf  *int64    `gorm:"uniqueIndex"`
+	Reason      string    `gorm:"not null;default:''"`
+	CreatedAt   time.Time `gorm:"not null"`
+}
+
+type AuditRecord struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement"`
+	Operation   string    `gorm:"size:32;not null"`
+	TransferID  *int64    `gorm:"index"`
+	FromAddress string    `gorm:"size:42;not null"`
+	ToAddress   string    `gorm:"size:42;not null"`
+	Amount      int64     `gorm:"not null"`
+	FromBalance int64     `gorm:"not null"`
+	ToBalance   int64     `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"not null"`
+	PrevHash    string    `gorm:"size:64;not null"`
+	Hash        string    `gorm:"size:64;not null;uniqueIndex"`
+}
+
+func (AuditRecord) TableName() string {
+	return "audit_log"
+}
+
+type JournalEntry struct {
+	ID         int64         `gorm:"primaryKey;autoIncrement"`
+	Kind       string        `gorm:"size:32;not null"`
+	TransferID *int64        `gorm:"index"`
+	CreatedAt  time.Time     `gorm:"not null"`
+	Lines      []JournalLine `gorm:"foreignKey:EntryID"`
+}
+
+type JournalLine struct {
+	ID      int64  `gorm:"primaryKey;autoIncrement"`
+	EntryID int64  `gorm:"index;not null"`
+	Account string `gorm:"size:42;index;not null"`
+	Amount  int64  `gorm:"not null"`
+}
+
+type BalanceCheckpoint struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	TakenAt   time.Time `gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+type CheckpointBalance struct {
+	CheckpointID int64  `gorm:"primaryKey"`
+	Account      string `gorm:"primaryKey;size:42"`
+	Balance      int64  `gorm:"not null"`
+}
+
+type Event struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	StreamID  string    `gorm:"size:64;index;not null"`
+	Type      string    `gorm:"size:64;not null"`
+	Payload   string    `gorm:"type:jsonb;not null"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+type EventBalance struct {
+	Address string `gorm:"primaryKey;size:42"`
 	Balance int64  `gorm:"not null"`
 }
+
+type EventTransfer struct {
+	ID          int64  `gorm:"primaryKey"`
+	FromAddress string `gorm:"size:42;not null"`
+	ToAddress   string `gorm:"size:42;not null"`
+	Amount      int64  `gorm:"not null"`
+	ReversalOf  *int64
+	Reason      string `gorm:"not null;default:''"`
+}
+
+type OutboxMessage struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement"`
+	EventType    string    `gorm:"size:64;not null"`
+	Payload      string    `gorm:"type:jsonb;not null"`
+	CreatedAt    time.Time `gorm:"not null"`
+	DispatchedAt *time.Time
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox"
+}
+
+type WebhookEndpoint struct {
+	ID                      int64  `gorm:"primaryKey;autoIncrement"`
+	URL                     string `gorm:"not null"`
+	Secret                  string `gorm:"not null"`
+	PreviousSecret          string `gorm:"not null;default:''"`
+	PreviousSecretExpiresAt *time.Time
+	Active                  bool      `gorm:"not null;default:true"`
+	CreatedAt               time.Time `gorm:"not null"`
+	UpdatedAt               time.Time `gorm:"not null"`
+}
+
+type WebhookDelivery struct {
+	ID            int64     `gorm:"primaryKey;autoIncrement"`
+	OutboxID      int64     `gorm:"not null;uniqueIndex:idx_webhook_deliveries_outbox_endpoint"`
+	EndpointID    int64     `gorm:"not null;uniqueIndex:idx_webhook_deliveries_outbox_endpoint"`
+	EventType     string    `gorm:"size:64;not null"`
+	Status        string    `gorm:"size:16;not null;index"`
+	Attempts      int       `gorm:"not null"`
+	NextAttemptAt time.Time `gorm:"not null"`
+	LastError     string    `gorm:"not null;default:''"`
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time        `gorm:"not null"`
+	AttemptLog    []WebhookAttempt `gorm:"foreignKey:DeliveryID"`
+}
+
+type WebhookAttempt struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement"`
+	DeliveryID  int64     `gorm:"index;not null"`
+	AttemptedAt time.Time `gorm:"not null"`
+	StatusCode  int       `gorm:"not null"`
+	Error       string    `gorm:"not null;default:''"`
+	DurationMs  int64     `gorm:"not null"`
+}
+
+// TransferRequest is a transfer submitted to the queue. TransferID and
+// SenderBalance are set once it has completed, Error once it has failed.
+type TransferRequest struct {
+	ID            int64  `gorm:"primaryKey;autoIncrement"`
+	FromAddress   string `gorm:"size:42;not null"`
+	ToAddress     string `gorm:"size:42;not null"`
+	Amount        int64  `gorm:"not null"`
+	Status        string `gorm:"size:16;not null"`
+	TransferID    *int64
+	SenderBalance *int64
+	Error         string    `gorm:"not null;default:''"`
+	CreatedAt     time.Time `gorm:"not null"`
+	ProcessedAt   *time.Time
+}
@@ -1,42 +1,10 @@
 package db
 
 import (
-	"fmt"
-	"log"
-	"os"
-
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func Connect() *gorm.DB {
-	dsn := fmt.Sprintf(
-		"host=db user=%s password=%s dbname=%s port=%s sslmode=disable",
-		os.Getenv("POSTGRES_USER"),
-		os.Getenv("POSTGRES_PASSWORD"),
-		os.Getenv("POSTGRES_DB"),
-		os.Getenv("POSTGRES_PORT"),
-	)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-
-	PingDatabase(err, db)
-
-	return db
-}
-
-func PingDatabase(err error, db *gorm.DB) {
-	sqlDB, err := db.DB()
-	if err != nil {
-		log.Fatalf("Failed to get raw DB: %v", err)
-	}
-
-	if err := sqlDB.Ping(); err != nil {
-		log.Fatalf("Database ping failed: %v", err)
-	}
-
-	log.Println("Ping to database succeeded.")
+func OpenPostgres(dsn string) (*gorm.DB, error) {
+	return gorm.Open(postgres.Open(dsn), &gorm.Config{})
 }
@@ -0,0 +1,24 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// OpenSQLite opens the SQLite database at path, creating the file if it
+// does not exist.
+//
+// SQLite has no SELECT ... FOR UPDATE, so every transaction is started with
+// BEGIN IMMEDIATE instead: it takes the database write lock up front, which
+// serializes write transactions and gives them the same guarantees the row
+// locks give on Postgres. Waiting transactions retry for up to the busy
+// timeout.
+func OpenSQLite(path string) (*gorm.DB, error) {
+	dsn := "file:" + path + "?_txlock=immediate&_busy_timeout=10000&_journal_mode=WAL&_foreign_keys=on"
+
+	return gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
+}
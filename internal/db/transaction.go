@@ -0,0 +1,209 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/metrics"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+// Postgres error codes of transactions that can succeed when run again.
+const (
+	CodeSerializationFailure = "40001"
+	CodeDeadlockDetected     = "40P01"
+)
+
+// CodeSQLiteBusy is reported for SQLite transactions that gave up waiting
+// for the database lock.
+const CodeSQLiteBusy = "SQLITE_BUSY"
+
+// CodeVersionConflict is reported for ErrVersionConflict.
+const CodeVersionConflict = "version_conflict"
+
+// ErrVersionConflict is returned by optimistic updates of a row that changed
+// after it was read. Transaction retries it like a serialization failure.
+var ErrVersionConflict = errors.New("wallet was modified concurrently")
+
+// RetryPolicy controls how Transaction retries transactions that failed with
+// a retryable error. The delay before retry n is a random duration up to
+// BaseDelay * 2^n, capped at MaxDelay.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 5,
+	BaseDelay:  10 * time.Millisecond,
+	MaxDelay:   time.Second,
+}
+
+var retryPolicy atomic.Pointer[RetryPolicy]
+
+func init() {
+	SetRetryPolicy(DefaultRetryPolicy)
+}
+
+// SetRetryPolicy replaces the policy used by Transaction.
+func SetRetryPolicy(policy RetryPolicy) {
+	retryPolicy.Store(&policy)
+}
+
+// Transaction runs fn in a transaction like database.Transaction, and runs
+// it again when the transaction fails with a serialization failure, a
+// deadlock or a busy SQLite database. fn must not have side effects outside
+// the transaction that cannot be repeated. Retries stop when the context of
+// database is done.
+//
+// Inside another transaction fn runs in a savepoint and is not retried,
+// because the failure aborts the enclosing transaction, which is retried
+// instead.
+func Transaction(database *gorm.DB, fn func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+	if committer, ok := database.Statement.ConnPool.(gorm.TxCommitter); ok && committer != nil {
+		return database.Transaction(fn)
+	}
+
+	started := time.Now()
+	err := retry(database, fn, opts...)
+
+	outcome := "commit"
+	if err != nil {
+		outcome = "rollback"
+	}
+
+	metrics.TransactionDuration.WithLabelValues(outcome).Observe(time.Since(started).Seconds())
+	return err
+}
+
+func retry(database *gorm.DB, fn func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+	policy := *retryPolicy.Load()
+	ctx := database.Statement.Context
+
+	for attempt := 0; ; attempt++ {
+		err := database.Transaction(fn, opts...)
+
+		code, retryable := RetryableCode(err)
+		if !retryable {
+			return err
+		}
+
+		if attempt >= policy.MaxRetries {
+			retries.record(code, true)
+			return fmt.Errorf("transaction failed after %d retries: %w", attempt, err)
+		}
+
+		retries.record(code, false)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(policy.delay(attempt)):
+		}
+	}
+}
+
+// RetryableCode returns the error code of err if running its transaction
+// again can succeed.
+func RetryableCode(err error) (string, bool) {
+	if errors.Is(err, ErrVersionConflict) {
+		return CodeVersionConflict, true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case CodeSerializationFailure, CodeDeadlockDetected:
+			return pgErr.Code, true
+		}
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrBusy {
+		return CodeSQLiteBusy, true
+	}
+
+	return "", false
+}
+
+// IsolationLevel returns the level named read-committed, repeatable-read or
+// serializable, and the database default for any other name.
+func IsolationLevel(name string) sql.IsolationLevel {
+	switch name {
+	case "read-committed":
+		return sql.LevelReadCommitted
+	case "repeatable-read":
+		return sql.LevelRepeatableRead
+	case "serializable":
+		return sql.LevelSerializable
+	default:
+		return sql.LevelDefault
+	}
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	limit := p.MaxDelay
+	if attempt < 32 && p.BaseDelay<<attempt < limit {
+		limit = p.BaseDelay << attempt
+	}
+
+	if limit <= 0 {
+		return 0
+	}
+
+	return rand.N(limit)
+}
+
+// RetryStats counts retryable transaction failures by error code. Retried
+// failures were run again; exhausted ones were returned to the caller after
+// the last retry.
+type RetryStats struct {
+	Retried   map[string]int64
+	Exhausted map[string]int64
+}
+
+type retryCounter struct {
+	mu    sync.Mutex
+	stats RetryStats
+}
+
+var retries = retryCounter{stats: RetryStats{Retried: map[string]int64{}, Exhausted: map[string]int64{}}}
+
+func (c *retryCounter) record(code string, exhausted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if exhausted {
+		c.stats.Exhausted[code]++
+		metrics.TransactionRetriesExhausted.WithLabelValues(code).Inc()
+	} else {
+		c.stats.Retried[code]++
+		metrics.TransactionRetries.WithLabelValues(code).Inc()
+	}
+}
+
+// TransactionRetryStats returns the retry counts since the process started.
+// They are also exported as metrics.TransactionRetries and
+// metrics.TransactionRetriesExhausted.
+func TransactionRetryStats() RetryStats {
+	retries.mu.Lock()
+	defer retries.mu.Unlock()
+
+	stats := RetryStats{Retried: map[string]int64{}, Exhausted: map[string]int64{}}
+	for code, count := range retries.stats.Retried {
+		stats.Retried[code] = count
+	}
+	for code, count := range retries.stats.Exhausted {
+		stats.Exhausted[code] = count
+	}
+
+	return stats
+}
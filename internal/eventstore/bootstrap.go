@@ -0,0 +1,145 @@
+package eventstore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"gorm.io/gorm"
+)
+
+// ControlStream holds the events that start and suspend event sourcing, and
+// the balance snapshots taken when it starts.
+const ControlStream = "event-sourcing"
+
+// Bootstrap starts event sourcing on a database whose event store is not
+// complete: one that has never been event sourced, or that ran with event
+// sourcing suspended. It snapshots every balance into the event store, so
+// that replay starts from them, and reports whether it did.
+func Bootstrap(database *gorm.DB) (bool, error) {
+	bootstrapped := false
+
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		bootstrapped = false
+
+		if err := lockBalances(tx); err != nil {
+			return err
+		}
+
+		last, err := lastControlEvent(tx)
+		if err != nil {
+			return err
+		}
+
+		if last == TypeEventSourcingStarted {
+			return nil
+		}
+
+		lastTransferID, err := lastTransferID(tx)
+		if err != nil {
+			return err
+		}
+
+		var balances []BalanceSnapshotted
+		err = tx.Raw(`
+			SELECT w.address AS address, ` + ledger.StoredBalanceSQL + ` AS balance
+			FROM wallets w
+			WHERE ` + ledger.StoredBalanceSQL + ` <> 0
+			ORDER BY w.address`).Scan(&balances).Error
+		if err != nil {
+			return fmt.Errorf("failed to read balances: %w", err)
+		}
+
+		events := make([]Event, 0, len(balances)+1)
+		events = append(events, EventSourcingStarted{LastTransferID: lastTransferID})
+		for _, balance := range balances {
+			events = append(events, balance)
+		}
+
+		bootstrapped = true
+		return Append(tx, ControlStream, events...)
+	})
+
+	if err != nil {
+		return false, fmt.Errorf("failed to bootstrap the event store: %w", err)
+	}
+
+	return bootstrapped, nil
+}
+
+// Suspend records that event sourcing is disabled, if it was started, so that
+// it is bootstrapped again when it is enabled. It reports whether it did.
+func Suspend(database *gorm.DB) (bool, error) {
+	suspended := false
+
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		suspended = false
+
+		last, err := lastControlEvent(tx)
+		if err != nil {
+			return err
+		}
+
+		if last != TypeEventSourcingStarted {
+			return nil
+		}
+
+		lastTransferID, err := lastTransferID(tx)
+		if err != nil {
+			return err
+		}
+
+		suspended = true
+		return Append(tx, ControlStream, EventSourcingSuspended{LastTransferID: lastTransferID})
+	})
+
+	if err != nil {
+		return false, fmt.Errorf("failed to suspend the event store: %w", err)
+	}
+
+	return suspended, nil
+}
+
+// lockBalances keeps transfers and issuances from committing until the
+// snapshot has been taken, so that none is both in it and in the events.
+func lockBalances(tx *gorm.DB) error {
+	// SQLite transactions already hold the database write lock.
+	if tx.Dialector.Name() == db.DriverSQLite {
+		return nil
+	}
+
+	if err := tx.Exec("LOCK TABLE wallets, wallet_shards, transfers IN SHARE MODE").Error; err != nil {
+		return fmt.Errorf("failed to lock balances: %w", err)
+	}
+
+	return nil
+}
+
+// lastControlEvent returns the type of the latest start or suspension of event
+// sourcing, or "" if there is none.
+func lastControlEvent(tx *gorm.DB) (string, error) {
+	var event db.Event
+
+	err := tx.Where("stream_id = ? AND type IN ?", ControlStream, []string{TypeEventSourcingStarted, TypeEventSourcingSuspended}).
+		Order("id DESC").
+		Take(&event).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read the event store: %w", err)
+	}
+
+	return event.Type, nil
+}
+
+func lastTransferID(tx *gorm.DB) (int64, error) {
+	var id int64
+	if err := tx.Model(&db.Transfer{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error; err != nil {
+		return 0, fmt.Errorf("failed to read the last transfer: %w", err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,299 @@
+package eventstore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"gorm.io/gorm"
+)
+
+const replayBatchSize = 500
+
+type BalanceMismatch struct {
+	Address       string `json:"address"`
+	LiveBalance   int64  `json:"live_balance"`
+	ReplayBalance int64  `json:"replay_balance"`
+}
+
+// ReplayResult compares the replayed projections with the live tables. The
+// transfers up to SnapshotTransferID predate the event store and are not
+// compared.
+type ReplayResult struct {
+	EventsReplayed      int64             `json:"events_replayed"`
+	SnapshotTransferID  int64             `json:"snapshot_transfer_id"`
+	BalanceMismatches   []BalanceMismatch `json:"balance_mismatches"`
+	MismatchedTransfers []int64           `json:"mismatched_transfers"`
+	Match               bool              `json:"match"`
+}
+
+// Replay rebuilds the event_balances and event_transfers projections from the
+// full event stream and compares them with the live wallets and transfers
+// tables, reading everything from one snapshot.
+func Replay(database *gorm.DB) (*ReplayResult, error) {
+	var result *ReplayResult
+
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		var err error
+		if result, err = project(tx); err != nil {
+			return err
+		}
+
+		return compare(tx, result)
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+
+	if err != nil {
+		return nil, err
+	}
+
+	result.Match = len(result.BalanceMismatches) == 0 && len(result.MismatchedTransfers) == 0
+	return result, nil
+}
+
+// Rebuild overwrites the live wallets.balance projection, less the balances
+// held by shards, and the transfers recorded since the event store was
+// bootstrapped with the projections replayed from the events, and returns the
+// number of rows it changed. Transfers the events do not know of are kept,
+// because the journal refers to them, and are still reported by Replay. It
+// should only be run while no transfers are in flight.
+func Rebuild(database *gorm.DB) (int64, error) {
+	var rebuilt int64
+
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		rebuilt = 0
+
+		if _, err := project(tx); err != nil {
+			return err
+		}
+
+		result := tx.Exec(`
+			INSERT INTO wallets (address, balance)
+			SELECT p.address, 0 FROM event_balances p
+			WHERE NOT EXISTS (SELECT 1 FROM wallets w WHERE w.address = p.address)`)
+		if result.Error != nil {
+			return fmt.Errorf("failed to create missing wallets: %w", result.Error)
+		}
+
+		result = tx.Exec(`
+			UPDATE wallets
+			SET balance = COALESCE((SELECT p.balance FROM event_balances p WHERE p.address = wallets.address), 0) - ` + ledger.ShardedSQL + `,
+				version = version + 1
+			WHERE balance <> COALESCE((SELECT p.balance FROM event_balances p WHERE p.address = wallets.address), 0) - ` + ledger.ShardedSQL)
+		if result.Error != nil {
+			return fmt.Errorf("failed to rebuild balances: %w", result.Error)
+		}
+
+		rebuilt += result.RowsAffected
+
+		transfers, err := rebuildTransfers(tx)
+		if err != nil {
+			return err
+		}
+
+		rebuilt += transfers
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return rebuilt, nil
+}
+
+// project rebuilds the event_balances and event_transfers projections from
+// the full event stream. A bootstrap supersedes the events before it: replay
+// restarts from its balance snapshot.
+func project(tx *gorm.DB) (*ReplayResult, error) {
+	result := &ReplayResult{BalanceMismatches: []BalanceMismatch{}, MismatchedTransfers: []int64{}}
+
+	if err := tx.Exec("DELETE FROM event_balances").Error; err != nil {
+		return nil, fmt.Errorf("failed to clear balance projection: %w", err)
+	}
+
+	if err := tx.Exec("DELETE FROM event_transfers").Error; err != nil {
+		return nil, fmt.Errorf("failed to clear transfer projection: %w", err)
+	}
+
+	balances := map[string]int64{}
+	var records []db.Event
+
+	err := tx.Model(&db.Event{}).FindInBatches(&records, replayBatchSize, func(batchTx *gorm.DB, batch int) error {
+		var transfers []db.EventTransfer
+
+		for i := range records {
+			event, err := decode(&records[i])
+			if err != nil {
+				return err
+			}
+
+			if started, ok := event.(*EventSourcingStarted); ok {
+				clear(balances)
+				transfers = nil
+				result.SnapshotTransferID = started.LastTransferID
+
+				if err := tx.Exec("DELETE FROM event_transfers").Error; err != nil {
+					return fmt.Errorf("failed to clear transfer projection: %w", err)
+				}
+
+				continue
+			}
+
+			if transfer := apply(balances, event); transfer != nil {
+				transfers = append(transfers, *transfer)
+			}
+		}
+
+		result.EventsReplayed += int64(len(records))
+
+		if len(transfers) == 0 {
+			return nil
+		}
+
+		return tx.Create(&transfers).Error
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay events: %w", err)
+	}
+
+	projection := make([]db.EventBalance, 0, len(balances))
+	for address, balance := range balances {
+		projection = append(projection, db.EventBalance{Address: address, Balance: balance})
+	}
+
+	if len(projection) > 0 {
+		if err := tx.CreateInBatches(&projection, replayBatchSize).Error; err != nil {
+			return nil, fmt.Errorf("failed to store balance projection: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// apply folds one event into the balances and returns the transfer history
+// row it produces, if any.
+func apply(balances map[string]int64, event Event) *db.EventTransfer {
+	switch e := event.(type) {
+	case *TokensIssued:
+		balances[e.Address] += e.Amount
+	case *BalanceSnapshotted:
+		balances[e.Address] = e.Balance
+	case *TransferCompleted:
+		if e.TransferID == 0 {
+			return nil
+		}
+
+		balances[e.From] -= e.Amount
+		balances[e.To] += e.Amount
+
+		return &db.EventTransfer{
+			ID:          e.TransferID,
+			FromAddress: e.From,
+			ToAddress:   e.To,
+			Amount:      e.Amount,
+			ReversalOf:  e.ReversalOf,
+			Reason:      e.Reason,
+		}
+	}
+
+	return nil
+}
+
+// rebuildTransfers writes the replayed transfers that are missing from or
+// differ in the transfers table to it, and returns how many it wrote.
+func rebuildTransfers(tx *gorm.DB) (int64, error) {
+	var changed []db.EventTransfer
+
+	err := tx.Raw(`
+		SELECT * FROM event_transfers
+		WHERE id IN (
+		    SELECT id FROM (
+		        SELECT id, from_address, to_address, amount, reversal_of, reason FROM event_transfers
+		        EXCEPT
+		        SELECT id, from_address, to_address, amount, reversal_of, reason FROM transfers
+		    ) AS changed
+		)
+		ORDER BY id`).Scan(&changed).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to compare transfers: %w", err)
+	}
+
+	inserted := false
+
+	for _, transfer := range changed {
+		result := tx.Model(&db.Transfer{}).Where("id = ?", transfer.ID).Updates(map[string]any{
+			"from_address": transfer.FromAddress,
+			"to_address":   transfer.ToAddress,
+			"amount":       transfer.Amount,
+			"reversal_of":  transfer.ReversalOf,
+			"reason":       transfer.Reason,
+		})
+		if result.Error != nil {
+			return 0, fmt.Errorf("failed to rebuild transfer %d: %w", transfer.ID, result.Error)
+		}
+
+		if result.RowsAffected > 0 {
+			continue
+		}
+
+		err := tx.Create(&db.Transfer{
+			ID:          transfer.ID,
+			FromAddress: transfer.FromAddress,
+			ToAddress:   transfer.ToAddress,
+			Amount:      transfer.Amount,
+			ReversalOf:  transfer.ReversalOf,
+			Reason:      transfer.Reason,
+			CreatedAt:   time.Now().UTC(),
+		}).Error
+		if err != nil {
+			return 0, fmt.Errorf("failed to rebuild transfer %d: %w", transfer.ID, err)
+		}
+
+		inserted = true
+	}
+
+	// Rows inserted with their id leave the Postgres sequence behind.
+	if inserted && tx.Dialector.Name() != db.DriverSQLite {
+		err := tx.Exec("SELECT setval(pg_get_serial_sequence('transfers', 'id'), (SELECT MAX(id) FROM transfers))").Error
+		if err != nil {
+			return 0, fmt.Errorf("failed to advance the transfers sequence: %w", err)
+		}
+	}
+
+	return int64(len(changed)), nil
+}
+
+func compare(tx *gorm.DB, result *ReplayResult) error {
+	err := tx.Raw(`
+		SELECT COALESCE(w.address, p.address) AS address,
+		       COALESCE(` + ledger.StoredBalanceSQL + `, 0) AS live_balance,
+		       COALESCE(p.balance, 0) AS replay_balance
+		FROM wallets w
+		FULL OUTER JOIN event_balances p ON p.address = w.address
+		WHERE COALESCE(` + ledger.StoredBalanceSQL + `, 0) <> COALESCE(p.balance, 0)
+		ORDER BY 1`).Scan(&result.BalanceMismatches).Error
+	if err != nil {
+		return fmt.Errorf("failed to compare balances: %w", err)
+	}
+
+	err = tx.Raw(`
+		SELECT id FROM (
+		    SELECT id, from_address, to_address, amount, reversal_of, reason FROM transfers WHERE id > ?
+		    EXCEPT
+		    SELECT id, from_address, to_address, amount, reversal_of, reason FROM event_transfers
+		) AS missing
+		UNION
+		SELECT id FROM (
+		    SELECT id, from_address, to_address, amount, reversal_of, reason FROM event_transfers
+		    EXCEPT
+		    SELECT id, from_address, to_address, amount, reversal_of, reason FROM transfers
+		) AS unexpected
+		ORDER BY id`, result.SnapshotTransferID).Scan(&result.MismatchedTransfers).Error
+	if err != nil {
+		return fmt.Errorf("failed to compare transfers: %w", err)
+	}
+
+	return nil
+}
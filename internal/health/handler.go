@@ -0,0 +1,147 @@
+// Package health serves the liveness and readiness probes of the API.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/migrate"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusFailing  = "failing"
+	StatusReady    = "ready"
+	StatusNotReady = "not ready"
+)
+
+// DefaultTimeout bounds the database queries of a readiness check.
+const DefaultTimeout = 2 * time.Second
+
+type Check struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type WorkersCheck struct {
+	Status  string                  `json:"status"`
+	Error   string                  `json:"error,omitempty"`
+	Workers map[string]WorkerStatus `json:"workers"`
+}
+
+type Report struct {
+	Status     string       `json:"status"`
+	Database   Check        `json:"database"`
+	Migrations Check        `json:"migrations"`
+	Workers    WorkersCheck `json:"workers"`
+}
+
+// Checker checks whether the API can serve requests: the database answers a
+// ping, every migration has been applied and every worker started is still
+// running.
+type Checker struct {
+	DB      *gorm.DB
+	Workers *Workers
+	Timeout time.Duration
+}
+
+func NewChecker(database *gorm.DB, workers *Workers) *Checker {
+	return &Checker{DB: database, Workers: workers, Timeout: DefaultTimeout}
+}
+
+// Ready runs every check. The database and migration checks share Timeout.
+func (c *Checker) Ready(ctx context.Context) *Report {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	report := &Report{
+		Status:     StatusReady,
+		Database:   c.checkDatabase(ctx),
+		Migrations: c.checkMigrations(ctx),
+		Workers:    c.checkWorkers(),
+	}
+
+	if report.Database.Status != StatusOK || report.Migrations.Status != StatusOK || report.Workers.Status != StatusOK {
+		report.Status = StatusNotReady
+	}
+
+	return report
+}
+
+func (c *Checker) checkDatabase(ctx context.Context) Check {
+	sqlDB, err := c.DB.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+
+	return result(err)
+}
+
+func (c *Checker) checkMigrations(ctx context.Context) Check {
+	return result(migrate.Check(c.DB.WithContext(ctx)))
+}
+
+func (c *Checker) checkWorkers() WorkersCheck {
+	check := WorkersCheck{Status: StatusOK, Workers: c.Workers.Status()}
+
+	var stopped []string
+	for _, name := range slices.Sorted(maps.Keys(check.Workers)) {
+		if status := check.Workers[name]; status.Running < status.Started {
+			stopped = append(stopped, name)
+		}
+	}
+
+	if len(stopped) > 0 {
+		check.Status = StatusFailing
+		check.Error = "stopped: " + strings.Join(stopped, ", ")
+	}
+
+	return check
+}
+
+func result(err error) Check {
+	if err != nil {
+		return Check{Status: StatusFailing, Error: err.Error()}
+	}
+
+	return Check{Status: StatusOK}
+}
+
+// LivenessHandler answers as long as the process serves HTTP.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, http.StatusOK, Check{Status: StatusOK})
+	})
+}
+
+// ReadinessHandler answers 200 with the report when checker is ready and
+// 503 with the report otherwise.
+func ReadinessHandler(checker *Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := checker.Ready(r.Context())
+
+		status := http.StatusOK
+		if report.Status != StatusReady {
+			status = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, r, status, report)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to write health response", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"sync"
+)
+
+// Workers keeps track of the background loops of the API, so readiness can
+// report whether they are running and shutdown can stop them and wait for
+// them to return.
+type Workers struct {
+	ctx     context.Context
+	stop    context.CancelFunc
+	mu      sync.Mutex
+	started map[string]int
+	running map[string]int
+	done    sync.WaitGroup
+}
+
+type WorkerStatus struct {
+	Started int `json:"started"`
+	Running int `json:"running"`
+}
+
+func NewWorkers() *Workers {
+	ctx, stop := context.WithCancel(context.Background())
+
+	return &Workers{
+		ctx:     ctx,
+		stop:    stop,
+		started: map[string]int{},
+		running: map[string]int{},
+	}
+}
+
+// Go runs loop in a new goroutine and counts it as a running worker called
+// name until it returns. loop must return soon after its context is
+// cancelled by Stop.
+func (w *Workers) Go(name string, loop func(ctx context.Context)) {
+	w.mu.Lock()
+	w.started[name]++
+	w.running[name]++
+	w.mu.Unlock()
+
+	w.done.Add(1)
+	go func() {
+		defer w.done.Done()
+		defer func() {
+			w.mu.Lock()
+			w.running[name]--
+			w.mu.Unlock()
+		}()
+
+		loop(w.ctx)
+	}()
+}
+
+// Stop cancels the context of every worker. Wait waits for them to return.
+func (w *Workers) Stop() {
+	w.stop()
+}
+
+// Status returns how many workers of every name were started and how many
+// of them are still running.
+func (w *Workers) Status() map[string]WorkerStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	status := make(map[string]WorkerStatus, len(w.started))
+	for name, started := range w.started {
+		status[name] = WorkerStatus{Started: started, Running: w.running[name]}
+	}
+
+	return status
+}
+
+// Wait blocks until every worker has returned or ctx is done, and returns
+// ctx's error in the latter case.
+func (w *Workers) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		w.done.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
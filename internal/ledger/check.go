@@ -0,0 +1,80 @@
+package ledger
+
+import (
+	"fmt"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"gorm.io/gorm"
+)
+
+type Discrepancy struct {
+	Address  string `json:"address"`
+	Stored   int64  `json:"stored"`
+	Computed int64  `json:"computed"`
+}
+
+type CheckResult struct {
+	Consistent        bool          `json:"consistent"`
+	Mismatches        []Discrepancy `json:"mismatches"`
+	UnbalancedEntries []int64       `json:"unbalanced_entries"`
+}
+
+// Check recomputes every wallet balance from the journal and compares it with
+// the wallets.balance projection. It also reports journal entries whose legs
+// do not sum to zero.
+func Check(database *gorm.DB) (*CheckResult, error) {
+	result := &CheckResult{Mismatches: []Discrepancy{}, UnbalancedEntries: []int64{}}
+
+	err := database.Raw(`
+		SELECT COALESCE(w.address, j.account) AS address,
+		       COALESCE(`+StoredBalanceSQL+`, 0) AS stored,
+		       COALESCE(j.total, 0) AS computed
+		FROM wallets w
+		FULL OUTER JOIN (
+		    SELECT account, SUM(amount) AS total
+		    FROM journal_lines
+		    WHERE account <> ?
+		    GROUP BY account
+		) j ON j.account = w.address
+		WHERE COALESCE(`+StoredBalanceSQL+`, 0) <> COALESCE(j.total, 0)
+		ORDER BY 1`, IssuanceAccount).Scan(&result.Mismatches).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to recompute balances: %w", err)
+	}
+
+	err = database.Raw(`
+		SELECT entry_id
+		FROM journal_lines
+		GROUP BY entry_id
+		HAVING SUM(amount) <> 0
+		ORDER BY entry_id`).Scan(&result.UnbalancedEntries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check journal entries: %w", err)
+	}
+
+	result.Consistent = len(result.Mismatches) == 0 && len(result.UnbalancedEntries) == 0
+	return result, nil
+}
+
+// Rebuild overwrites the wallets.balance projection with balances recomputed
+// from the journal, less the balances held by shards. It should only be run
+// while no transfers are in flight.
+func Rebuild(database *gorm.DB) (int64, error) {
+	var rebuilt int64
+
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		result := tx.Exec(`
+			UPDATE wallets
+			SET balance = COALESCE((SELECT SUM(amount) FROM journal_lines WHERE account = wallets.address), 0) - ` + ShardedSQL + `,
+				version = version + 1
+			WHERE balance <> COALESCE((SELECT SUM(amount) FROM journal_lines WHERE account = wallets.address), 0) - ` + ShardedSQL)
+		if result.Error != nil {
+			return fmt.Errorf("failed to rebuild balances: %w", result.Error)
+		}
+
+		rebuilt = result.RowsAffected
+		return nil
+	})
+
+	return rebuilt, err
+}
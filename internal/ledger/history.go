@@ -0,0 +1,188 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"gorm.io/gorm"
+)
+
+// CheckpointLag keeps checkpoints behind the wall clock so that transactions
+// which started before the checkpoint time but committed after it are still
+// included in the checkpoint.
+const CheckpointLag = time.Minute
+
+type AccountBalance struct {
+	Address string `json:"address"`
+	Balance int64  `json:"balance"`
+}
+
+// BalanceAt returns the balance of address as of the given time, starting
+// from the latest checkpoint taken at or before it.
+func BalanceAt(database *gorm.DB, address string, at time.Time) (int64, error) {
+	checkpoint, err := latestCheckpoint(database, at)
+	if err != nil {
+		return 0, err
+	}
+
+	var base int64
+
+	if checkpoint != nil {
+		err := database.Model(&db.CheckpointBalance{}).
+			Select("COALESCE(SUM(balance), 0)").
+			Where("checkpoint_id = ? AND account = ?", checkpoint.ID, address).
+			Scan(&base).Error
+		if err != nil {
+			return 0, fmt.Errorf("failed to read checkpoint balance: %w", err)
+		}
+	}
+
+	var delta int64
+
+	err = linesBetween(database, checkpoint, at).
+		Select("COALESCE(SUM(journal_lines.amount), 0)").
+		Where("journal_lines.account = ?", address).
+		Scan(&delta).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum journal lines: %w", err)
+	}
+
+	return base + delta, nil
+}
+
+// SnapshotAt returns the balance of every wallet as of the given time,
+// ordered by address.
+func SnapshotAt(database *gorm.DB, at time.Time) ([]AccountBalance, error) {
+	checkpoint, err := latestCheckpoint(database, at)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := []AccountBalance{}
+
+	err = database.Raw(`
+		SELECT account AS address, SUM(amount) AS balance
+		FROM (?) AS movements
+		WHERE account <> ?
+		GROUP BY account
+		ORDER BY account`, movementsSince(database, checkpoint, at), IssuanceAccount).
+		Scan(&balances).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute snapshot: %w", err)
+	}
+
+	return balances, nil
+}
+
+// TakeCheckpoint stores the balance of every account as of asOf. Checkpoints
+// must be taken in chronological order.
+func TakeCheckpoint(database *gorm.DB, asOf time.Time) (*db.BalanceCheckpoint, error) {
+	asOf = asOf.UTC().Truncate(time.Microsecond)
+	var checkpoint *db.BalanceCheckpoint
+
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		var newest db.BalanceCheckpoint
+		if err := tx.Order("taken_at DESC").Limit(1).Find(&newest).Error; err != nil {
+			return fmt.Errorf("failed to read latest checkpoint: %w", err)
+		}
+
+		if newest.ID != 0 && !asOf.After(newest.TakenAt) {
+			return fmt.Errorf("checkpoint at %s is not after the latest checkpoint at %s",
+				asOf.Format(time.RFC3339), newest.TakenAt.Format(time.RFC3339))
+		}
+
+		var previous *db.BalanceCheckpoint
+		if newest.ID != 0 {
+			previous = &newest
+		}
+
+		checkpoint = &db.BalanceCheckpoint{TakenAt: asOf}
+		if err := tx.Create(checkpoint).Error; err != nil {
+			return fmt.Errorf("failed to create checkpoint: %w", err)
+		}
+
+		err := tx.Exec(`
+			INSERT INTO checkpoint_balances (checkpoint_id, account, balance)
+			SELECT ?, account, SUM(amount)
+			FROM (?) AS movements
+			GROUP BY account`, checkpoint.ID, movementsSince(tx, previous, asOf)).Error
+		if err != nil {
+			return fmt.Errorf("failed to store checkpoint balances: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+// RunCheckpointJob takes a checkpoint every interval, lagging behind the
+// wall clock by CheckpointLag. It returns when ctx is cancelled.
+func RunCheckpointJob(ctx context.Context, database *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := TakeCheckpoint(database.WithContext(ctx), time.Now().Add(-CheckpointLag)); err != nil {
+				slog.ErrorContext(ctx, "Balance checkpoint failed", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+func latestCheckpoint(database *gorm.DB, at time.Time) (*db.BalanceCheckpoint, error) {
+	var checkpoint db.BalanceCheckpoint
+
+	err := database.Where("taken_at <= ?", at).Order("taken_at DESC").First(&checkpoint).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to find checkpoint: %w", err)
+	}
+
+	return &checkpoint, nil
+}
+
+// linesBetween selects the journal lines posted after checkpoint (or since
+// the beginning when it is nil) up to and including at.
+func linesBetween(database *gorm.DB, checkpoint *db.BalanceCheckpoint, at time.Time) *gorm.DB {
+	query := database.Model(&db.JournalLine{}).
+		Joins("JOIN journal_entries ON journal_entries.id = journal_lines.entry_id").
+		Where("journal_entries.created_at <= ?", at)
+
+	if checkpoint != nil {
+		query = query.Where("journal_entries.created_at > ?", checkpoint.TakenAt)
+	}
+
+	return query
+}
+
+// movementsSince yields (account, amount) rows whose per-account sums are the
+// balances at the given time: the checkpoint balances plus later lines.
+func movementsSince(database *gorm.DB, checkpoint *db.BalanceCheckpoint, at time.Time) *gorm.DB {
+	lines := linesBetween(database.Session(&gorm.Session{NewDB: true}), checkpoint, at).
+		Select("journal_lines.account AS account, journal_lines.amount AS amount")
+
+	if checkpoint == nil {
+		return lines
+	}
+
+	return database.Session(&gorm.Session{NewDB: true}).Raw(`
+		SELECT account, balance AS amount FROM checkpoint_balances WHERE checkpoint_id = ?
+		UNION ALL
+		?`, checkpoint.ID, lines)
+}
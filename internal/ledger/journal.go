@@ -0,0 +1,277 @@
+package ledger
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dominika232323/token-transfer-api/internal/audit"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"gorm.io/gorm"
+)
+
+// IssuanceAccount is the system account new tokens are issued from. Its
+// balance is the negative of the total supply and it has no wallet.
+const IssuanceAccount = "issuance"
+
+const (
+	KindIssuance = "issuance"
+	KindOpening  = "opening"
+	KindTransfer = "transfer"
+	KindReversal = "reversal"
+)
+
+// Leg is one side of a journal entry. Negative amounts debit the account,
+// positive amounts credit it. AllowNegative lets a debit of a hot wallet take
+// its shards below zero; the balances of other wallets are checked by the
+// caller.
+type Leg struct {
+	Account       string
+	Amount        int64
+	AllowNegative bool
+}
+
+func IsSystemAccount(account string) bool {
+	return account == IssuanceAccount
+}
+
+// Post records a balanced journal entry and applies its legs to the
+// wallets.balance projection, or to the shards of hot wallets. The wallets
+// must already exist and, unless they are hot, should be locked by the
+// caller. A debit of a hot wallet returns ErrInsufficientFunds if its shards
+// do not cover it, unless the leg allows a negative balance.
+func Post(tx *gorm.DB, kind string, transferID *int64, legs ...Leg) (*db.JournalEntry, error) {
+	entry, err := record(tx, kind, transferID, legs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, leg := range legs {
+		if IsSystemAccount(leg.Account) {
+			continue
+		}
+
+		result := tx.Model(&db.Wallet{}).
+			Where("address = ? AND shards = 0", leg.Account).
+			Updates(map[string]any{
+				"balance": gorm.Expr("balance + ?", leg.Amount),
+				"version": gorm.Expr("version + 1"),
+			})
+
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to update balance of %s: %w", leg.Account, result.Error)
+		}
+
+		if result.RowsAffected == 1 {
+			continue
+		}
+
+		var wallet db.Wallet
+		if err := tx.Select("shards").Where("address = ?", leg.Account).Take(&wallet).Error; err != nil || wallet.Shards == 0 {
+			return nil, fmt.Errorf("wallet %s not found", leg.Account)
+		}
+
+		if err := applyToShards(tx, wallet.Shards, leg); err != nil {
+			return nil, err
+		}
+	}
+
+	return entry, nil
+}
+
+// PostVersioned records a balanced journal entry like Post, for wallets that
+// were read without locking them. A debit is applied only if its wallet
+// still has the version it was read at and, if the balance it was read with
+// covered the debit, still covers it. Otherwise PostVersioned returns
+// db.ErrVersionConflict and the transaction must be rolled back. Credits do
+// not depend on the balance, so they are applied whatever the version.
+// wallets are updated in place to their new balance and version. Legs of hot
+// wallets are applied to their shards as by Post.
+func PostVersioned(tx *gorm.DB, kind string, transferID *int64, wallets []*db.Wallet, legs ...Leg) (*db.JournalEntry, error) {
+	read := map[string]*db.Wallet{}
+	for _, wallet := range wallets {
+		read[wallet.Address] = wallet
+	}
+
+	entry, err := record(tx, kind, transferID, legs)
+	if err != nil {
+		return nil, err
+	}
+
+	// The updates lock the wallets, so apply them in address order, like
+	// pessimistic locking, to avoid deadlocks.
+	ordered := append([]Leg(nil), legs...)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Account < ordered[j].Account })
+
+	for _, leg := range ordered {
+		if IsSystemAccount(leg.Account) {
+			continue
+		}
+
+		wallet, ok := read[leg.Account]
+		if !ok {
+			return nil, fmt.Errorf("wallet %s was not read", leg.Account)
+		}
+
+		if wallet.Shards > 0 {
+			if err := applyToShards(tx, wallet.Shards, leg); err != nil {
+				return nil, err
+			}
+
+			wallet.Balance += leg.Amount
+			continue
+		}
+
+		query := tx.Model(&db.Wallet{}).Where("address = ? AND shards = 0", leg.Account)
+		if leg.Amount < 0 {
+			query = query.Where("version = ?", wallet.Version)
+
+			if wallet.Balance >= -leg.Amount {
+				query = query.Where("balance >= ?", -leg.Amount)
+			}
+		}
+
+		result := query.Updates(map[string]any{
+			"balance": gorm.Expr("balance + ?", leg.Amount),
+			"version": gorm.Expr("version + 1"),
+		})
+
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to update balance of %s: %w", leg.Account, result.Error)
+		}
+
+		if result.RowsAffected != 1 {
+			return nil, db.ErrVersionConflict
+		}
+
+		// The update locked the wallet, so its new state can be read back.
+		if err := tx.Select("balance", "version").Where("address = ?", leg.Account).Take(wallet).Error; err != nil {
+			return nil, fmt.Errorf("failed to read balance of %s: %w", leg.Account, err)
+		}
+	}
+
+	return entry, nil
+}
+
+func record(tx *gorm.DB, kind string, transferID *int64, legs []Leg) (*db.JournalEntry, error) {
+	if len(legs) < 2 {
+		return nil, fmt.Errorf("journal entry needs at least two legs")
+	}
+
+	var sum int64
+	entry := &db.JournalEntry{Kind: kind, TransferID: transferID}
+
+	for _, leg := range legs {
+		sum += leg.Amount
+		entry.Lines = append(entry.Lines, db.JournalLine{Account: leg.Account, Amount: leg.Amount})
+	}
+
+	if sum != 0 {
+		return nil, fmt.Errorf("journal entry is unbalanced: legs sum to %d", sum)
+	}
+
+	if err := tx.Create(entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to record journal entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+// Issue creates amount new tokens in the wallet at address, creating the
+// wallet if needed.
+func Issue(tx *gorm.DB, address string, amount int64) error {
+	if amount <= 0 {
+		return fmt.Errorf("issued amount must be positive")
+	}
+
+	return db.Transaction(tx, func(tx *gorm.DB) error {
+		if _, err := LockWallet(tx, address); err != nil {
+			return err
+		}
+
+		if _, err := Post(tx, KindIssuance, nil,
+			Leg{Account: IssuanceAccount, Amount: -amount},
+			Leg{Account: address, Amount: amount},
+		); err != nil {
+			return err
+		}
+
+		// The shards of a hot wallet are not locked, so its balance is read
+		// under the audit lock, in the order of the chain.
+		if err := audit.Lock(tx); err != nil {
+			return err
+		}
+
+		issued, err := FindWallet(tx, address)
+		if err != nil {
+			return fmt.Errorf("failed to read wallet %s: %w", address, err)
+		}
+
+		return audit.Append(tx, &db.AuditRecord{
+			Operation:   audit.OperationIssuance,
+			FromAddress: IssuanceAccount,
+			ToAddress:   address,
+			Amount:      amount,
+			ToBalance:   issued.Balance,
+		})
+	})
+}
+
+// Opening is a balance OpenBalances carried into the journal.
+type Opening struct {
+	Address string
+	Amount  int64
+}
+
+// OpenBalances posts an opening entry from the issuance account for every
+// wallet whose stored balance exceeds its journal balance, such as wallets
+// funded before the journal existed, and records it in the audit log. The
+// stored balances already hold these amounts and are left unchanged. It
+// returns the openings it posted, by address.
+func OpenBalances(tx *gorm.DB) ([]Opening, error) {
+	var missing []struct {
+		Address string
+		Amount  int64
+		Stored  int64
+	}
+
+	err := tx.Raw(`
+		SELECT w.address AS address,
+		       ` + StoredBalanceSQL + ` - COALESCE(j.total, 0) AS amount,
+		       ` + StoredBalanceSQL + ` AS stored
+		FROM wallets w
+		LEFT JOIN (
+		    SELECT account, SUM(amount) AS total
+		    FROM journal_lines
+		    GROUP BY account
+		) j ON j.account = w.address
+		WHERE ` + StoredBalanceSQL + ` > COALESCE(j.total, 0)
+		ORDER BY w.address`).Scan(&missing).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find balances missing from the journal: %w", err)
+	}
+
+	openings := make([]Opening, 0, len(missing))
+
+	for _, wallet := range missing {
+		if _, err := record(tx, KindOpening, nil, []Leg{
+			{Account: IssuanceAccount, Amount: -wallet.Amount},
+			{Account: wallet.Address, Amount: wallet.Amount},
+		}); err != nil {
+			return nil, err
+		}
+
+		if err := audit.Append(tx, &db.AuditRecord{
+			Operation:   audit.OperationIssuance,
+			FromAddress: IssuanceAccount,
+			ToAddress:   wallet.Address,
+			Amount:      wallet.Amount,
+			ToBalance:   wallet.Stored,
+		}); err != nil {
+			return nil, err
+		}
+
+		openings = append(openings, Opening{Address: wallet.Address, Amount: wallet.Amount})
+	}
+
+	return openings, nil
+}
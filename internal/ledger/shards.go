@@ -0,0 +1,262 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientFunds is returned when the shards of a hot wallet do not
+// hold enough tokens for a debit. The transfer package returns it as
+// ErrInsufficientBalance.
+var ErrInsufficientFunds = errors.New("Insufficient balance")
+
+// StoredBalanceSQL is the SQL expression for the stored balance of the
+// wallet aliased w, including the balances of its shards.
+const StoredBalanceSQL = "(w.balance + COALESCE((SELECT SUM(s.balance) FROM wallet_shards s WHERE s.address = w.address), 0))"
+
+// ShardedSQL is the SQL expression for the balance held by the shards of the
+// row of the wallets table being updated.
+const ShardedSQL = "COALESCE((SELECT SUM(s.balance) FROM wallet_shards s WHERE s.address = wallets.address), 0)"
+
+// FindWallet reads the wallet at address, with the balances of its shards
+// added to Balance, without locking it. It returns gorm.ErrRecordNotFound if
+// there is no such wallet.
+func FindWallet(tx *gorm.DB, address string) (*db.Wallet, error) {
+	var wallet db.Wallet
+
+	if err := tx.Where("address = ?", address).Take(&wallet).Error; err != nil {
+		return nil, err
+	}
+
+	if wallet.Shards == 0 {
+		return &wallet, nil
+	}
+
+	var sharded int64
+	err := tx.Model(&db.WalletShard{}).
+		Select("COALESCE(SUM(balance), 0)").
+		Where("address = ?", address).
+		Scan(&sharded).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum shards of %s: %w", address, err)
+	}
+
+	wallet.Balance += sharded
+	return &wallet, nil
+}
+
+// CreateWallet creates an empty wallet at address unless there is one. When
+// another transaction is creating the same wallet, the insert waits for it
+// and then does nothing, where FirstOrCreate would fail with a unique
+// violation.
+func CreateWallet(tx *gorm.DB, address string) error {
+	err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "address"}}, DoNothing: true}).
+		Create(&db.Wallet{Address: address}).Error
+	if err != nil {
+		return fmt.Errorf("failed to create wallet %s: %w", address, err)
+	}
+
+	return nil
+}
+
+// LockWallet creates the wallet at address if needed and locks it until the
+// transaction ends. The balances of its shards are not added to Balance.
+func LockWallet(tx *gorm.DB, address string) (*db.Wallet, error) {
+	if err := CreateWallet(tx, address); err != nil {
+		return nil, err
+	}
+
+	var wallet db.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("address = ?", address).Take(&wallet).Error; err != nil {
+		return nil, fmt.Errorf("failed to lock wallet %s: %w", address, err)
+	}
+
+	return &wallet, nil
+}
+
+// ConfigureShards makes the wallets at addresses hot wallets with shards
+// shards each, spreading their balances evenly, and folds the shards of every
+// other wallet back into wallets.balance. It must not run while transfers
+// are in flight.
+func ConfigureShards(database *gorm.DB, addresses []string, shards int) error {
+	if len(addresses) > 0 && shards < 1 {
+		return fmt.Errorf("hot wallets need at least one shard")
+	}
+
+	hot := map[string]bool{}
+	for _, address := range addresses {
+		hot[address] = true
+	}
+
+	return db.Transaction(database, func(tx *gorm.DB) error {
+		var sharded []db.Wallet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("shards > 0").Find(&sharded).Error; err != nil {
+			return fmt.Errorf("failed to read sharded wallets: %w", err)
+		}
+
+		for i := range sharded {
+			if !hot[sharded[i].Address] {
+				if err := reshard(tx, &sharded[i], 0); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, address := range addresses {
+			wallet, err := LockWallet(tx, address)
+			if err != nil {
+				return err
+			}
+
+			if wallet.Shards == shards && wallet.Balance == 0 {
+				continue
+			}
+
+			if err := reshard(tx, wallet, shards); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// reshard replaces the shards of the locked wallet with shards new ones that
+// split its whole balance evenly. With 0 shards the balance moves back to
+// wallets.balance.
+func reshard(tx *gorm.DB, wallet *db.Wallet, shards int) error {
+	var existing []db.WalletShard
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("address = ?", wallet.Address).Find(&existing).Error; err != nil {
+		return fmt.Errorf("failed to lock shards of %s: %w", wallet.Address, err)
+	}
+
+	total := wallet.Balance
+	for _, shard := range existing {
+		total += shard.Balance
+	}
+
+	if err := tx.Where("address = ?", wallet.Address).Delete(&db.WalletShard{}).Error; err != nil {
+		return fmt.Errorf("failed to remove shards of %s: %w", wallet.Address, err)
+	}
+
+	balance := total
+	if shards > 0 {
+		balance = 0
+
+		created := make([]db.WalletShard, shards)
+		for i := range created {
+			created[i] = db.WalletShard{Address: wallet.Address, Shard: i, Balance: total / int64(shards)}
+		}
+		created[0].Balance += total % int64(shards)
+
+		if err := tx.Create(&created).Error; err != nil {
+			return fmt.Errorf("failed to create shards of %s: %w", wallet.Address, err)
+		}
+	}
+
+	err := tx.Model(wallet).Updates(map[string]any{
+		"balance": balance,
+		"shards":  shards,
+		"version": gorm.Expr("version + 1"),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update wallet %s: %w", wallet.Address, err)
+	}
+
+	return nil
+}
+
+// applyToShards applies leg to the shards of a hot wallet with shards shards.
+// A credit goes to a random shard. A debit comes from a random shard that
+// covers it and is not locked by another transaction, or, if there is none,
+// from as many shards as it takes. If the leg allows a negative balance, what
+// the shards do not cover is taken from the last one.
+func applyToShards(tx *gorm.DB, shards int, leg Leg) error {
+	if leg.Amount >= 0 {
+		result := tx.Model(&db.WalletShard{}).
+			Where("address = ? AND shard = ?", leg.Account, rand.IntN(shards)).
+			Update("balance", gorm.Expr("balance + ?", leg.Amount))
+		if result.Error != nil {
+			return fmt.Errorf("failed to credit shard of %s: %w", leg.Account, result.Error)
+		}
+
+		if result.RowsAffected != 1 {
+			return fmt.Errorf("wallet %s has no shards", leg.Account)
+		}
+
+		return nil
+	}
+
+	amount := -leg.Amount
+
+	var candidates []db.WalletShard
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("address = ? AND balance >= ?", leg.Account, amount).
+		Order("random()").
+		Limit(1).
+		Find(&candidates).Error
+	if err != nil {
+		return fmt.Errorf("failed to pick shard of %s: %w", leg.Account, err)
+	}
+
+	if len(candidates) == 1 {
+		return debitShard(tx, candidates[0], amount)
+	}
+
+	var all []db.WalletShard
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("address = ?", leg.Account).
+		Order("shard").
+		Find(&all).Error
+	if err != nil {
+		return fmt.Errorf("failed to lock shards of %s: %w", leg.Account, err)
+	}
+
+	var total int64
+	for _, shard := range all {
+		total += shard.Balance
+	}
+
+	if total < amount && !leg.AllowNegative {
+		return ErrInsufficientFunds
+	}
+
+	for i, shard := range all {
+		if amount == 0 {
+			break
+		}
+
+		taken := min(max(shard.Balance, 0), amount)
+		if i == len(all)-1 {
+			taken = amount
+		}
+
+		if taken == 0 {
+			continue
+		}
+
+		if err := debitShard(tx, shard, taken); err != nil {
+			return err
+		}
+
+		amount -= taken
+	}
+
+	return nil
+}
+
+func debitShard(tx *gorm.DB, shard db.WalletShard, amount int64) error {
+	err := tx.Model(&db.WalletShard{}).
+		Where("address = ? AND shard = ?", shard.Address, shard.Shard).
+		Update("balance", gorm.Expr("balance - ?", amount)).Error
+	if err != nil {
+		return fmt.Errorf("failed to debit shard %d of %s: %w", shard.Shard, shard.Address, err)
+	}
+
+	return nil
+}
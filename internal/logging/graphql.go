@@ -0,0 +1,28 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ErrorPresenter logs every error a resolver returns, with its request ID,
+// and adds the request ID to the error's extensions so clients can quote it.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	presented := graphql.DefaultErrorPresenter(ctx, err)
+
+	slog.WarnContext(ctx, "GraphQL resolver failed",
+		slog.String("path", presented.Path.String()),
+		slog.String("error", err.Error()))
+
+	if id := RequestID(ctx); id != "" {
+		if presented.Extensions == nil {
+			presented.Extensions = map[string]any{}
+		}
+		presented.Extensions["request_id"] = id
+	}
+
+	return presented
+}
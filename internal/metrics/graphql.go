@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// GraphQL is a gqlgen handler extension that counts operations and times
+// them and their field resolvers. Operations are labelled with their first
+// root field rather than their client-chosen name, which keeps the number
+// of label values bounded by the schema.
+type GraphQL struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+	graphql.FieldInterceptor
+} = GraphQL{}
+
+func (GraphQL) ExtensionName() string {
+	return "Metrics"
+}
+
+func (GraphQL) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (GraphQL) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	started := time.Now()
+	kind, field := describe(graphql.GetOperationContext(ctx).Operation)
+	respond := next(ctx)
+
+	// Subscriptions call the response handler once per event; only the first
+	// response is measured.
+	var once sync.Once
+
+	return func(ctx context.Context) *graphql.Response {
+		response := respond(ctx)
+
+		once.Do(func() {
+			outcome := "ok"
+			if response != nil && len(response.Errors) > 0 {
+				outcome = "error"
+			}
+
+			GraphQLOperations.WithLabelValues(kind, field, outcome).Inc()
+			GraphQLOperationDuration.WithLabelValues(kind, field).Observe(time.Since(started).Seconds())
+		})
+
+		return response
+	}
+}
+
+func (GraphQL) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	field := graphql.GetFieldContext(ctx)
+	if field == nil || !field.IsResolver {
+		return next(ctx)
+	}
+
+	started := time.Now()
+	result, err := next(ctx)
+	ResolverDuration.WithLabelValues(field.Object, field.Field.Name).Observe(time.Since(started).Seconds())
+
+	return result, err
+}
+
+func describe(operation *ast.OperationDefinition) (string, string) {
+	if operation == nil {
+		return "unknown", "unknown"
+	}
+
+	for _, selection := range operation.SelectionSet {
+		if field, ok := selection.(*ast.Field); ok {
+			return string(operation.Operation), field.Name
+		}
+	}
+
+	return string(operation.Operation), "unknown"
+}
@@ -0,0 +1,98 @@
+// Package metrics holds the Prometheus metrics of the API, served on
+// /metrics by Handler.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "token_transfer"
+
+// Registry holds every metric below together with the Go runtime and
+// process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// Transfers counts transfers and reversals by outcome ("completed" or
+	// "failed") and, for failures, by transfer.ErrorCode.
+	Transfers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_total",
+		Help:      "Transfers and reversals by operation, outcome and error code.",
+	}, []string{"operation", "outcome", "code"})
+
+	GraphQLOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "graphql_operations_total",
+		Help:      "GraphQL operations by type, root field and outcome.",
+	}, []string{"type", "field", "outcome"})
+
+	GraphQLOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "graphql_operation_duration_seconds",
+		Help:      "Time until the first response of a GraphQL operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type", "field"})
+
+	ResolverDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "graphql_resolver_duration_seconds",
+		Help:      "Latency of GraphQL field resolvers.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"object", "field"})
+
+	TransactionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_transaction_duration_seconds",
+		Help:      "Latency of database transactions, including retries, by outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"outcome"})
+
+	TransactionRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_transaction_retries_total",
+		Help:      "Transactions run again after a serialization failure, deadlock or version conflict, by error code.",
+	}, []string{"code"})
+
+	TransactionRetriesExhausted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_transaction_retries_exhausted_total",
+		Help:      "Transactions that still failed after the last retry, by error code.",
+	}, []string{"code"})
+
+	LockWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_lock_wait_seconds",
+		Help:      "Time spent acquiring wallet, transfer and audit chain locks.",
+		Buckets:   []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"lock"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Transfers,
+		GraphQLOperations,
+		GraphQLOperationDuration,
+		ResolverDuration,
+		TransactionDuration,
+		TransactionRetries,
+		TransactionRetriesExhausted,
+		LockWait,
+	)
+}
+
+// RegisterDatabase exports the connection pool stats of database.
+func RegisterDatabase(database *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(database, "token_transfer"))
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,269 @@
+package migrate
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"gorm.io/gorm"
+)
+
+// migrationLockKey serializes concurrent migration runs on Postgres.
+const migrationLockKey = 727002
+
+//go:embed migrations
+var files embed.FS
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrNotMigrated is returned by Check when migrations are pending.
+var ErrNotMigrated = errors.New("database schema is not up to date, run the migrate command")
+
+// Migration is one schema change. Every driver has its own SQL for each
+// version under migrations/<driver>. Step, if set, runs after the up SQL.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	Step    func(tx *gorm.DB) error
+}
+
+// SchemaMigration records an applied migration.
+type SchemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Load returns the migrations for the driver of database, oldest first.
+func Load(database *gorm.DB) ([]Migration, error) {
+	dir := path.Join("migrations", database.Dialector.Name())
+
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for driver %s: %w", database.Dialector.Name(), err)
+	}
+
+	byVersion := map[int64]*Migration{}
+
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+
+		content, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2], Step: steps[version]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies every pending migration, each in its own transaction, and
+// returns the ones it applied.
+func Up(database *gorm.DB) ([]Migration, error) {
+	migrations, err := Load(database)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied := []Migration{}
+
+	for _, migration := range migrations {
+		done := false
+
+		err := db.Transaction(database, func(tx *gorm.DB) error {
+			done = false
+
+			if err := lock(tx); err != nil {
+				return err
+			}
+
+			var count int64
+			if err := tx.Model(&SchemaMigration{}).Where("version = ?", migration.Version).Count(&count).Error; err != nil {
+				return fmt.Errorf("failed to read schema_migrations: %w", err)
+			}
+
+			if count > 0 {
+				return nil
+			}
+
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+
+			if migration.Step != nil {
+				if err := migration.Step(tx); err != nil {
+					return fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+				}
+			}
+
+			done = true
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+
+		if err != nil {
+			return applied, err
+		}
+
+		if done {
+			applied = append(applied, migration)
+		}
+	}
+
+	return applied, nil
+}
+
+// Down reverts the latest steps applied migrations, newest first, and
+// returns the ones it reverted.
+func Down(database *gorm.DB, steps int) ([]Migration, error) {
+	statuses, err := List(database)
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := []Migration{}
+
+	for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := statuses[i].Migration
+		if statuses[i].AppliedAt == nil {
+			continue
+		}
+
+		err := db.Transaction(database, func(tx *gorm.DB) error {
+			if err := lock(tx); err != nil {
+				return err
+			}
+
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return fmt.Errorf("reverting migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		})
+
+		if err != nil {
+			return reverted, err
+		}
+
+		reverted = append(reverted, migration)
+	}
+
+	return reverted, nil
+}
+
+// List returns every known migration with the time it was applied, if it
+// was.
+func List(database *gorm.DB) ([]Status, error) {
+	migrations, err := Load(database)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []SchemaMigration{}
+
+	if database.Migrator().HasTable(&SchemaMigration{}) {
+		if err := database.Find(&applied).Error; err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+	}
+
+	appliedAt := map[int64]time.Time{}
+	for _, record := range applied {
+		appliedAt[record.Version] = record.AppliedAt
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, migration := range migrations {
+		status := Status{Migration: migration}
+
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Check returns ErrNotMigrated unless every migration has been applied.
+func Check(database *gorm.DB) error {
+	statuses, err := List(database)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			return fmt.Errorf("%w: migration %04d_%s is pending", ErrNotMigrated, status.Version, status.Name)
+		}
+	}
+
+	return nil
+}
+
+func lock(tx *gorm.DB) error {
+	// SQLite transactions already hold the database write lock.
+	if tx.Dialector.Name() == db.DriverSQLite {
+		return nil
+	}
+
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+		return fmt.Errorf("failed to lock schema_migrations: %w", err)
+	}
+
+	return nil
+}
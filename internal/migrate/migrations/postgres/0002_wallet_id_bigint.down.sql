@@ -0,0 +1,2 @@
+ALTER SEQUENCE wallets_id_seq AS INTEGER;
+ALTER TABLE wallets ALTER COLUMN id TYPE INTEGER;
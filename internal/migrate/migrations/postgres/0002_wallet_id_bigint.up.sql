@@ -0,0 +1,4 @@
+-- Databases created by the original init script have a 32-bit wallets.id,
+-- while db.Wallet uses int64.
+ALTER TABLE wallets ALTER COLUMN id TYPE BIGINT;
+ALTER SEQUENCE wallets_id_seq AS BIGINT;
@@ -0,0 +1,7 @@
+UPDATE wallets
+SET balance = balance + COALESCE((SELECT SUM(balance) FROM wallet_shards s WHERE s.address = wallets.address), 0),
+    version = version + 1
+WHERE shards > 0;
+
+DROP TABLE IF EXISTS wallet_shards;
+ALTER TABLE wallets DROP COLUMN IF EXISTS shards;
@@ -0,0 +1,11 @@
+-- Hot wallets keep their balance in wallet_shards, so that concurrent
+-- transfers do not all wait for the lock on one wallets row. The balance of a
+-- wallet is wallets.balance plus the balances of its shards.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS shards INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS wallet_shards (
+    address VARCHAR(42) NOT NULL REFERENCES wallets (address),
+    shard INTEGER NOT NULL,
+    balance BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (address, shard)
+);
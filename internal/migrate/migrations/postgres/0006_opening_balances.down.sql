@@ -0,0 +1,3 @@
+-- The opening entries are kept: the audit log that records them is
+-- append-only, and the stored balances depend on them to reconcile.
+SELECT 1;
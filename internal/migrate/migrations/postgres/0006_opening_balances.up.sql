@@ -0,0 +1,5 @@
+-- Balances funded before the journal existed, such as by the old init
+-- scripts, are carried into it by the Go step of this migration (see
+-- steps.go), which posts an opening entry from the issuance account for each
+-- wallet and records it in the audit log and the event store.
+SELECT 1;
@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS webhook_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_endpoints;
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS event_transfers;
+DROP TABLE IF EXISTS event_balances;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS checkpoint_balances;
+DROP TABLE IF EXISTS balance_checkpoints;
+DROP TABLE IF EXISTS journal_lines;
+DROP TABLE IF EXISTS journal_entries;
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS transfers;
+DROP TABLE IF EXISTS wallets;
@@ -0,0 +1 @@
+-- SQLite INTEGER PRIMARY KEY ids are already 64-bit.
@@ -0,0 +1,3 @@
+-- Incremented on every balance change, so that optimistic locking can
+-- detect wallets that changed after they were read.
+ALTER TABLE wallets ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
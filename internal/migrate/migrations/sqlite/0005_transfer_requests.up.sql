@@ -0,0 +1,17 @@
+-- Transfers submitted with submitTransfer wait here until a queue worker
+-- processes them. A request is processed in the transaction that performs its
+-- transfer, so it is never applied twice, even across restarts.
+CREATE TABLE IF NOT EXISTS transfer_requests (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    from_address VARCHAR(42) NOT NULL,
+    to_address VARCHAR(42) NOT NULL,
+    amount BIGINT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    transfer_id BIGINT REFERENCES transfers (id),
+    sender_balance BIGINT,
+    error TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    processed_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_requests_pending ON transfer_requests (from_address, id) WHERE status = 'pending';
@@ -0,0 +1,57 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/eventstore"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"gorm.io/gorm"
+)
+
+const (
+	GenesisAddress = "0x0000000000000000000000000000000000000000"
+	GenesisSupply  = 1000000
+)
+
+// Seed issues the genesis supply if the journal is empty and no wallet holds
+// a balance, and reports whether it did. Balances funded before the journal
+// existed are carried into it by the migrations instead.
+func Seed(database *gorm.DB) (bool, error) {
+	seeded := false
+
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		seeded = false
+
+		if err := lock(tx); err != nil {
+			return err
+		}
+
+		var entries int64
+		if err := tx.Model(&db.JournalEntry{}).Count(&entries).Error; err != nil {
+			return fmt.Errorf("failed to check for existing journal entries: %w", err)
+		}
+
+		if entries > 0 {
+			return nil
+		}
+
+		var funded int64
+		if err := tx.Table("wallets w").Where(ledger.StoredBalanceSQL + " <> 0").Count(&funded).Error; err != nil {
+			return fmt.Errorf("failed to check for funded wallets: %w", err)
+		}
+
+		if funded > 0 {
+			return nil
+		}
+
+		seeded = true
+		return eventstore.Issue(tx, GenesisAddress, GenesisSupply)
+	})
+
+	if err != nil {
+		return false, fmt.Errorf("failed to seed genesis supply: %w", err)
+	}
+
+	return seeded, nil
+}
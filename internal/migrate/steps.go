@@ -0,0 +1,35 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/dominika232323/token-transfer-api/internal/eventstore"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"gorm.io/gorm"
+)
+
+// steps are the Go parts of migrations, by version, for changes SQL cannot
+// make, such as records in the hash-chained audit log. A step runs after the
+// migration's up SQL, in the same transaction.
+var steps = map[int64]func(tx *gorm.DB) error{
+	6: openBalances,
+}
+
+// openBalances carries the balances that predate the journal into it and
+// records a TokensIssued event for each, as if the tokens had been issued.
+func openBalances(tx *gorm.DB) error {
+	openings, err := ledger.OpenBalances(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, opening := range openings {
+		event := eventstore.TokensIssued{Address: opening.Address, Amount: opening.Amount}
+
+		if err := eventstore.Append(tx, eventstore.WalletStream(opening.Address), event); err != nil {
+			return fmt.Errorf("failed to record opening balance of %s: %w", opening.Address, err)
+		}
+	}
+
+	return nil
+}
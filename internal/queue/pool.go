@@ -0,0 +1,184 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Pool processes queued transfer requests. Each worker takes the oldest
+// pending requests of one sender, up to BatchSize, and performs them in
+// order in a single transaction, so no two workers ever process the same
+// sender at once and a batch costs one commit.
+type Pool struct {
+	DB           *gorm.DB
+	Store        transfer.StoreOptions
+	Service      transfer.Options
+	Workers      int
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+func NewPool(database *gorm.DB, store transfer.StoreOptions, service transfer.Options) *Pool {
+	return &Pool{
+		DB:           database,
+		Store:        store,
+		Service:      service,
+		Workers:      4,
+		BatchSize:    100,
+		PollInterval: 100 * time.Millisecond,
+	}
+}
+
+// Start runs Workers workers until ctx is cancelled. A worker keeps taking
+// batches while there are pending requests and waits PollInterval when there
+// are none. Cancelling ctx stops the workers from taking new batches; a batch
+// already taken is finished first.
+func (p *Pool) Start(ctx context.Context) {
+	go p.Run(ctx)
+}
+
+// Run runs the workers like Start and returns once ctx is cancelled and
+// every worker has finished its batch.
+func (p *Pool) Run(ctx context.Context) {
+	var workers sync.WaitGroup
+
+	for range p.Workers {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			p.work(ctx)
+		}()
+	}
+
+	workers.Wait()
+}
+
+func (p *Pool) work(ctx context.Context) {
+	for {
+		processed, err := p.RunOnce(context.WithoutCancel(ctx))
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Transfer queue batch failed", slog.String("error", err.Error()))
+		}
+
+		if err == nil && processed > 0 && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.PollInterval):
+		}
+	}
+}
+
+// RunOnce processes one batch and returns the number of requests in it.
+func (p *Pool) RunOnce(ctx context.Context) (int, error) {
+	processed := 0
+
+	err := db.Transaction(p.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		processed = 0
+
+		requests, err := p.claim(tx)
+		if err != nil || len(requests) == 0 {
+			return err
+		}
+
+		// The store shares the batch transaction, so every transfer runs in
+		// a savepoint and commits together with its request.
+		service := transfer.NewService(transfer.NewGormStore(tx, p.Store), p.Service)
+
+		for i := range requests {
+			if err := process(ctx, tx, service, &requests[i]); err != nil {
+				return err
+			}
+			processed++
+		}
+
+		return nil
+	})
+
+	return processed, err
+}
+
+// claim locks the next batch: the oldest pending requests of a sender whose
+// first pending request no other worker has locked.
+func (p *Pool) claim(tx *gorm.DB) ([]db.TransferRequest, error) {
+	skipLocked := clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}
+
+	var head db.TransferRequest
+
+	result := tx.Clauses(skipLocked).
+		Where("status = ?", StatusPending).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM transfer_requests earlier
+			WHERE earlier.from_address = transfer_requests.from_address
+			  AND earlier.status = ? AND earlier.id < transfer_requests.id)`, StatusPending).
+		Order("id").
+		Limit(1).
+		Find(&head)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to read transfer queue: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	var requests []db.TransferRequest
+
+	err := tx.Clauses(skipLocked).
+		Where("from_address = ? AND status = ?", head.FromAddress, StatusPending).
+		Order("id").
+		Limit(p.BatchSize).
+		Find(&requests).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queued transfers of %s: %w", head.FromAddress, err)
+	}
+
+	return requests, nil
+}
+
+// process performs request and records its outcome. Errors worth retrying
+// abort the batch, so that it is run again as a whole; any other error fails
+// only the request.
+func process(ctx context.Context, tx *gorm.DB, service transfer.Service, request *db.TransferRequest) error {
+	result, err := service.Transfer(ctx, request.FromAddress, request.ToAddress, request.Amount)
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if _, retryable := db.RetryableCode(err); retryable {
+		return err
+	}
+
+	now := time.Now()
+	request.ProcessedAt = &now
+
+	if err != nil {
+		request.Status = StatusFailed
+		request.Error = err.Error()
+	} else {
+		request.Status = StatusCompleted
+		request.SenderBalance = &result.SenderBalance
+
+		if result.Transfer != nil {
+			request.TransferID = &result.Transfer.ID
+		}
+	}
+
+	if err := tx.Save(request).Error; err != nil {
+		return fmt.Errorf("failed to update transfer request %d: %w", request.ID, err)
+	}
+
+	return nil
+}
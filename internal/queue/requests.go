@@ -0,0 +1,107 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// WatchInterval is how often Watch polls a pending request. Requests are
+// polled rather than announced so that a subscriber sees requests processed
+// by other instances.
+var WatchInterval = 200 * time.Millisecond
+
+var ErrRequestNotFound = errors.New("transfer request not found")
+
+// Submit queues a transfer and returns the pending request. Negative amounts
+// and addresses too long to store are rejected right away; every other check
+// happens when a worker processes the request.
+func Submit(database *gorm.DB, fromAddress string, toAddress string, amount int64) (*db.TransferRequest, error) {
+	if amount < 0 {
+		return nil, transfer.ErrNegativeAmount
+	}
+
+	if err := transfer.ValidateAddresses(fromAddress, toAddress); err != nil {
+		return nil, err
+	}
+
+	request := &db.TransferRequest{
+		FromAddress: fromAddress,
+		ToAddress:   toAddress,
+		Amount:      amount,
+		Status:      StatusPending,
+	}
+
+	if err := database.Create(request).Error; err != nil {
+		return nil, fmt.Errorf("failed to queue transfer: %w", err)
+	}
+
+	return request, nil
+}
+
+func Find(database *gorm.DB, id int64) (*db.TransferRequest, error) {
+	var request db.TransferRequest
+
+	if err := database.First(&request, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRequestNotFound
+		}
+		return nil, fmt.Errorf("failed to read transfer request %d: %w", id, err)
+	}
+
+	return &request, nil
+}
+
+// Watch sends the request with the given id, and again every time its status
+// changes, until it has completed or failed or ctx is cancelled. The channel
+// is closed after the last update.
+func Watch(ctx context.Context, database *gorm.DB, id int64) (<-chan *db.TransferRequest, error) {
+	request, err := Find(database.WithContext(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan *db.TransferRequest, 1)
+	updates <- request
+
+	go func() {
+		defer close(updates)
+
+		ticker := time.NewTicker(WatchInterval)
+		defer ticker.Stop()
+
+		for request.Status == StatusPending {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := Find(database.WithContext(ctx), id)
+			if err != nil || current.Status == request.Status {
+				continue
+			}
+
+			request = current
+
+			select {
+			case <-ctx.Done():
+				return
+			case updates <- request:
+			}
+		}
+	}()
+
+	return updates, nil
+}
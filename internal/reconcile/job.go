@@ -0,0 +1,36 @@
+package reconcile
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RunJob runs a reconciliation every interval and logs any drift it finds.
+// It returns when ctx is cancelled.
+func RunJob(ctx context.Context, database *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := Run(database.WithContext(ctx))
+			if err != nil {
+				slog.ErrorContext(ctx, "Reconciliation failed", slog.String("error", err.Error()))
+				continue
+			}
+
+			if !report.Reconciled {
+				slog.WarnContext(ctx, "Reconciliation found drift",
+					slog.Int64("supply_drift", report.SupplyDrift),
+					slog.Int("wallet_drifts", len(report.WalletDrifts)),
+					slog.Int("unbalanced_entries", len(report.UnbalancedEntries)))
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package rest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+const maxRequestBody = 1 << 20
+
+type transferRequest struct {
+	FromAddress string `json:"from_address"`
+	ToAddress   string `json:"to_address"`
+	Amount      *int64 `json:"amount"`
+}
+
+type transferResponse struct {
+	Transfer *transferJSON `json:"transfer"`
+	Balance  int64         `json:"balance"`
+}
+
+type transferJSON struct {
+	ID          int64     `json:"id"`
+	FromAddress string    `json:"from_address"`
+	ToAddress   string    `json:"to_address"`
+	Amount      int64     `json:"amount"`
+	ReversalOf  *int64    `json:"reversal_of"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type walletJSON struct {
+	Address string `json:"address"`
+	Balance int64  `json:"balance"`
+}
+
+type historyResponse struct {
+	Transfers  []transferJSON `json:"transfers"`
+	NextBefore *int64         `json:"next_before"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewHandler serves the /v1 REST API on top of the transfer service.
+func NewHandler(service transfer.Service) http.Handler {
+	h := &handler{service: service}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/transfers", h.createTransfer)
+	mux.HandleFunc("GET /v1/wallets/{address}", h.getWallet)
+	mux.HandleFunc("GET /v1/wallets/{address}/transfers", h.listTransfers)
+	mux.HandleFunc("GET /v1/openapi.yaml", serveOpenAPI)
+
+	return mux
+}
+
+type handler struct {
+	service transfer.Service
+}
+
+func (h *handler) createTransfer(w http.ResponseWriter, r *http.Request) {
+	var request transferRequest
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if request.FromAddress == "" || request.ToAddress == "" || request.Amount == nil {
+		writeError(w, r, http.StatusBadRequest, "from_address, to_address and amount are required")
+		return
+	}
+
+	result, err := h.service.Transfer(r.Context(), request.FromAddress, request.ToAddress, *request.Amount)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	response := transferResponse{Balance: result.SenderBalance}
+	if result.Transfer != nil {
+		response.Transfer = toTransferJSON(result.Transfer)
+	}
+
+	writeJSON(w, r, http.StatusCreated, response)
+}
+
+func (h *handler) getWallet(w http.ResponseWriter, r *http.Request) {
+	wallet, err := h.service.GetWallet(r.Context(), r.PathValue("address"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, walletJSON{Address: wallet.Address, Balance: wallet.Balance})
+}
+
+func (h *handler) listTransfers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := transfer.DefaultHistoryLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	var before int64
+	if value := query.Get("before"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			writeError(w, r, http.StatusBadRequest, "invalid before")
+			return
+		}
+		before = parsed
+	}
+
+	if limit <= 0 || limit > transfer.MaxHistoryLimit {
+		writeError(w, r, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(transfer.MaxHistoryLimit))
+		return
+	}
+
+	transfers, err := h.service.History(r.Context(), r.PathValue("address"), limit, before)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	response := historyResponse{Transfers: make([]transferJSON, 0, len(transfers))}
+	for i := range transfers {
+		response.Transfers = append(response.Transfers, *toTransferJSON(&transfers[i]))
+	}
+
+	if len(transfers) == limit {
+		next := transfers[len(transfers)-1].ID
+		response.NextBefore = &next
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(openAPISpec)
+}
+
+func toTransferJSON(transfer *db.Transfer) *transferJSON {
+	return &transferJSON{
+		ID:          transfer.ID,
+		FromAddress: transfer.FromAddress,
+		ToAddress:   transfer.ToAddress,
+		Amount:      transfer.Amount,
+		ReversalOf:  transfer.ReversalOf,
+		Reason:      transfer.Reason,
+		CreatedAt:   transfer.CreatedAt,
+	}
+}
+
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, transfer.ErrNegativeAmount), errors.Is(err, transfer.ErrInvalidAddress):
+		writeError(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, transfer.ErrInsufficientBalance):
+		writeError(w, r, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, transfer.ErrWalletNotFound):
+		writeError(w, r, http.StatusNotFound, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "REST request failed", slog.String("error", err.Error()))
+		writeError(w, r, http.StatusInternalServerError, "internal error")
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeJSON(w, r, status, errorResponse{Error: message})
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to write REST response", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dominika232323/token-transfer-api/internal/logging"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const (
+	CodeInternal        = "INTERNAL_SERVER_ERROR"
+	CodeRequestTooLarge = "REQUEST_TOO_LARGE"
+)
+
+const internalErrorMessage = "internal server error"
+
+// corsMaxAge is how many seconds browsers may cache a preflight answer.
+const corsMaxAge = 600
+
+var (
+	corsMethods = []string{http.MethodGet, http.MethodPost, http.MethodOptions}
+	corsHeaders = []string{"Authorization", "Content-Type", logging.RequestIDHeader, "traceparent", "tracestate"}
+)
+
+// Recover answers requests whose handler panics with a GraphQL-shaped 500
+// error carrying the request ID, and logs the panic with its stack.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			// The handler wants the connection dropped without a response.
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			slog.ErrorContext(r.Context(), "HTTP handler panicked",
+				slog.String("path", r.URL.Path),
+				slog.Any("panic", recovered),
+				slog.String("stack", string(debug.Stack())))
+
+			writeGraphQLError(w, r, http.StatusInternalServerError, internalErrorMessage, CodeInternal)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RecoverResolver is the recover function of the GraphQL handler. It logs a
+// resolver's panic with its stack and hides it from the client.
+func RecoverResolver(ctx context.Context, recovered any) error {
+	slog.ErrorContext(ctx, "GraphQL resolver panicked",
+		slog.Any("panic", recovered),
+		slog.String("stack", string(debug.Stack())))
+
+	return &gqlerror.Error{
+		Message:    internalErrorMessage,
+		Extensions: map[string]any{"code": CodeInternal},
+	}
+}
+
+// LimitBody rejects request bodies larger than maxBytes with a GraphQL-shaped
+// 413 error before next reads them.
+func LimitBody(maxBytes int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBytes {
+			writeTooLarge(w, r, maxBytes)
+			return
+		}
+
+		if r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Bodies of unknown length are read here, so that exceeding the
+		// limit gets the same answer as an oversized Content-Length.
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeTooLarge(w, r, maxBytes)
+				return
+			}
+
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CORS lets the pages of the allowed origins call the API from a browser.
+type CORS struct {
+	origins   []string
+	anyOrigin bool
+}
+
+func NewCORS(origins []string) *CORS {
+	return &CORS{origins: origins, anyOrigin: slices.Contains(origins, "*")}
+}
+
+// Allows reports whether pages of origin may call the API.
+func (c *CORS) Allows(origin string) bool {
+	return c.anyOrigin || slices.Contains(c.origins, origin)
+}
+
+// CheckOrigin decides whether a WebSocket upgrade is accepted: requests
+// without an Origin header, from the API's own origin or from an allowed
+// origin are.
+func (c *CORS) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || c.Allows(origin) {
+		return true
+	}
+
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, r.Host)
+}
+
+// Middleware adds the CORS headers to responses to allowed origins and
+// answers their preflight requests. Preflight requests from other origins
+// are refused with 403; their other requests are served without the headers,
+// so browsers withhold the response from the page.
+func (c *CORS) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if !c.Allows(origin) {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", logging.RequestIDHeader)
+
+		if !preflight {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(corsMethods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(corsHeaders, ", "))
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+type graphQLErrorResponse struct {
+	Errors []*gqlerror.Error `json:"errors"`
+	Data   any               `json:"data"`
+}
+
+func writeTooLarge(w http.ResponseWriter, r *http.Request, maxBytes int64) {
+	message := fmt.Sprintf("request body exceeds %d bytes", maxBytes)
+	writeGraphQLError(w, r, http.StatusRequestEntityTooLarge, message, CodeRequestTooLarge)
+}
+
+func writeGraphQLError(w http.ResponseWriter, r *http.Request, status int, message string, code string) {
+	extensions := map[string]any{"code": code}
+	if id := logging.RequestID(r.Context()); id != "" {
+		extensions["request_id"] = id
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	response := graphQLErrorResponse{Errors: []*gqlerror.Error{{Message: message, Extensions: extensions}}}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to write error response", slog.String("error", err.Error()))
+	}
+}
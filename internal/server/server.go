@@ -0,0 +1,89 @@
+// Package server runs the HTTP server and the background workers of the API
+// and shuts them down without cutting off transfers in flight.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/config"
+	"github.com/dominika232323/token-transfer-api/internal/health"
+	"gorm.io/gorm"
+)
+
+type Server struct {
+	HTTP            *http.Server
+	Workers         *health.Workers
+	DB              *gorm.DB
+	ShutdownTimeout time.Duration
+}
+
+func New(handler http.Handler, workers *health.Workers, database *gorm.DB, cfg config.Server) *Server {
+	return &Server{
+		HTTP: &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			ReadTimeout:       cfg.ReadTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+		Workers:         workers,
+		DB:              database,
+		ShutdownTimeout: cfg.ShutdownTimeout,
+	}
+}
+
+// Run serves HTTP on listener until ctx is done, then shuts down. It returns
+// early if serving fails, after shutting down as well.
+func (s *Server) Run(ctx context.Context, listener net.Listener) error {
+	served := make(chan error, 1)
+	go func() {
+		served <- s.HTTP.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return errors.Join(fmt.Errorf("failed to serve HTTP: %w", err), s.Shutdown())
+	case <-ctx.Done():
+		slog.Info("Shutting down", slog.Duration("timeout", s.ShutdownTimeout))
+		return s.Shutdown()
+	}
+}
+
+// Shutdown stops accepting requests and stops the workers, waits up to
+// ShutdownTimeout for the requests in flight and the workers to finish, and
+// closes the database pool. Transactions still running after the timeout
+// are rolled back when their connection is closed.
+func (s *Server) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+	defer cancel()
+
+	s.Workers.Stop()
+
+	var errs []error
+
+	if err := s.HTTP.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to drain HTTP requests: %w", err))
+		_ = s.HTTP.Close()
+	}
+
+	if err := s.Workers.Wait(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to drain workers: %w", err))
+	}
+
+	sqlDB, err := s.DB.DB()
+	if err == nil {
+		err = sqlDB.Close()
+	}
+
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to close database: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,96 @@
+package statement
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Handler serves GET /statements/{address}?from=...&to=...&format=csv|jsonl|text.
+func Handler(database *gorm.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		address := r.PathValue("address")
+		query := r.URL.Query()
+
+		format, err := ParseFormat(query.Get("format"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		from, err := ParseTime(query.Get("from"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid from: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		to, err := ParseTime(query.Get("to"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid to: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		// Statements are streamed and may take longer than the server's
+		// write timeout allows for other responses.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		out := &deferredHeaderWriter{ResponseWriter: w, format: format, address: address}
+
+		err = Write(database.WithContext(r.Context()), out, address, from, to, format)
+		if err == nil {
+			return
+		}
+
+		if out.started {
+			slog.WarnContext(r.Context(), "Statement aborted", slog.String("address", address), slog.String("error", err.Error()))
+			return
+		}
+
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrWalletNotFound) {
+			status = http.StatusNotFound
+		} else if !to.After(from) {
+			status = http.StatusBadRequest
+		}
+
+		http.Error(w, err.Error(), status)
+	})
+}
+
+// ParseTime accepts either an RFC 3339 timestamp or a YYYY-MM-DD date, which
+// is interpreted as midnight UTC.
+func ParseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("missing time")
+	}
+
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+
+	return time.Parse(time.DateOnly, value)
+}
+
+// deferredHeaderWriter sends the success headers only once the first byte of
+// the statement is written, so errors before that can still become proper
+// HTTP error responses.
+type deferredHeaderWriter struct {
+	http.ResponseWriter
+	format  Format
+	address string
+	started bool
+}
+
+func (d *deferredHeaderWriter) Write(p []byte) (int, error) {
+	if !d.started {
+		d.started = true
+		d.Header().Set("Content-Type", d.format.ContentType())
+		d.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "statement-"+d.address+"."+string(d.format)))
+	}
+
+	return d.ResponseWriter.Write(p)
+}
@@ -0,0 +1,139 @@
+package statement
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"gorm.io/gorm"
+)
+
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+	FormatText  Format = "text"
+)
+
+const (
+	LineOpening  = "opening_balance"
+	LineMovement = "movement"
+	LineClosing  = "closing_balance"
+)
+
+var ErrWalletNotFound = errors.New("wallet not found")
+
+// Line is one row of a statement. Opening and closing lines only carry a
+// time and a balance.
+type Line struct {
+	Type         string    `json:"type"`
+	At           time.Time `json:"at"`
+	EntryID      int64     `json:"entry_id,omitempty"`
+	Kind         string    `json:"kind,omitempty"`
+	TransferID   *int64    `json:"transfer_id,omitempty"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	Amount       int64     `json:"amount"`
+	Balance      int64     `json:"balance"`
+}
+
+type lineWriter interface {
+	Write(line *Line) error
+	Flush() error
+}
+
+func ParseFormat(value string) (Format, error) {
+	switch Format(value) {
+	case FormatCSV, FormatJSONL, FormatText:
+		return Format(value), nil
+	case "":
+		return FormatCSV, nil
+	}
+
+	return "", fmt.Errorf("unsupported statement format %q", value)
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatJSONL:
+		return "application/jsonl"
+	case FormatText:
+		return "text/plain; charset=utf-8"
+	}
+
+	return "text/csv"
+}
+
+// Write streams the statement of the wallet at address for movements after
+// from up to and including to. Rows are read with a cursor so long histories
+// are never held in memory.
+func Write(database *gorm.DB, w io.Writer, address string, from time.Time, to time.Time, format Format) error {
+	if !to.After(from) {
+		return fmt.Errorf("statement range end must be after its start")
+	}
+
+	var wallet db.Wallet
+	if err := database.Where("address = ?", address).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrWalletNotFound
+		}
+		return fmt.Errorf("failed to find wallet %s: %w", address, err)
+	}
+
+	opening, err := ledger.BalanceAt(database, wallet.Address, from)
+	if err != nil {
+		return err
+	}
+
+	out := newLineWriter(w, format, wallet.Address)
+
+	if err := out.Write(&Line{Type: LineOpening, At: from, Balance: opening}); err != nil {
+		return err
+	}
+
+	rows, err := database.Raw(`
+		SELECT e.id, e.kind, e.transfer_id, e.created_at, l.amount,
+		       COALESCE((
+		           SELECT other.account FROM journal_lines other
+		           WHERE other.entry_id = e.id AND other.account <> l.account
+		           ORDER BY other.id LIMIT 1
+		       ), '') AS counterparty
+		FROM journal_lines l
+		JOIN journal_entries e ON e.id = l.entry_id
+		WHERE l.account = ? AND e.created_at > ? AND e.created_at <= ?
+		ORDER BY e.created_at, e.id`, wallet.Address, from, to).Rows()
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+	defer rows.Close()
+
+	balance := opening
+
+	for rows.Next() {
+		line := Line{Type: LineMovement}
+
+		if err := rows.Scan(&line.EntryID, &line.Kind, &line.TransferID, &line.At, &line.Amount, &line.Counterparty); err != nil {
+			return fmt.Errorf("failed to read journal line: %w", err)
+		}
+
+		balance += line.Amount
+		line.Balance = balance
+
+		if err := out.Write(&line); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	if err := out.Write(&Line{Type: LineClosing, At: to, Balance: balance}); err != nil {
+		return err
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,105 @@
+package statement
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+func newLineWriter(w io.Writer, format Format, address string) lineWriter {
+	switch format {
+	case FormatJSONL:
+		buffered := bufio.NewWriter(w)
+		return &jsonlWriter{buffered: buffered, encoder: json.NewEncoder(buffered)}
+	case FormatText:
+		return &textWriter{out: bufio.NewWriter(w), address: address}
+	}
+
+	return &csvWriter{out: csv.NewWriter(w)}
+}
+
+type csvWriter struct {
+	out           *csv.Writer
+	headerWritten bool
+}
+
+func (c *csvWriter) Write(line *Line) error {
+	if !c.headerWritten {
+		c.headerWritten = true
+
+		header := []string{"type", "at", "entry_id", "kind", "transfer_id", "counterparty", "amount", "balance"}
+		if err := c.out.Write(header); err != nil {
+			return err
+		}
+	}
+
+	entryID, transferID := "", ""
+	if line.EntryID != 0 {
+		entryID = strconv.FormatInt(line.EntryID, 10)
+	}
+	if line.TransferID != nil {
+		transferID = strconv.FormatInt(*line.TransferID, 10)
+	}
+
+	return c.out.Write([]string{
+		line.Type,
+		line.At.UTC().Format(time.RFC3339Nano),
+		entryID,
+		line.Kind,
+		transferID,
+		line.Counterparty,
+		strconv.FormatInt(line.Amount, 10),
+		strconv.FormatInt(line.Balance, 10),
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	c.out.Flush()
+	return c.out.Error()
+}
+
+type jsonlWriter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+func (j *jsonlWriter) Write(line *Line) error {
+	return j.encoder.Encode(line)
+}
+
+func (j *jsonlWriter) Flush() error {
+	return j.buffered.Flush()
+}
+
+type textWriter struct {
+	out     *bufio.Writer
+	address string
+}
+
+func (t *textWriter) Write(line *Line) error {
+	var err error
+
+	switch line.Type {
+	case LineOpening:
+		_, err = fmt.Fprintf(t.out, "Statement for %s\n\n%-30s %-15s %-44s %15s %15s\n%-30s %-15s %-44s %15s %15d\n",
+			t.address,
+			"Date", "Kind", "Counterparty", "Amount", "Balance",
+			line.At.UTC().Format(time.RFC3339), "opening", "", "", line.Balance)
+	case LineClosing:
+		_, err = fmt.Fprintf(t.out, "%-30s %-15s %-44s %15s %15d\n",
+			line.At.UTC().Format(time.RFC3339), "closing", "", "", line.Balance)
+	default:
+		_, err = fmt.Fprintf(t.out, "%-30s %-15s %-44s %15d %15d\n",
+			line.At.UTC().Format(time.RFC3339), line.Kind, line.Counterparty, line.Amount, line.Balance)
+	}
+
+	return err
+}
+
+func (t *textWriter) Flush() error {
+	return t.out.Flush()
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GraphQL is a gqlgen handler extension that traces every operation and
+// every field resolved by a resolver.
+type GraphQL struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+	graphql.FieldInterceptor
+} = GraphQL{}
+
+func (GraphQL) ExtensionName() string {
+	return "Tracing"
+}
+
+func (GraphQL) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptOperation names the span after the operation, or after its type
+// when it is anonymous. A subscription's span ends with its first response.
+func (GraphQL) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	operation := graphql.GetOperationContext(ctx)
+
+	kind, operationName := "operation", operation.OperationName
+	if operation.Operation != nil {
+		kind = string(operation.Operation.Operation)
+
+		if operationName == "" {
+			operationName = operation.Operation.Name
+		}
+	}
+
+	name := kind
+	if operationName != "" {
+		name = kind + " " + operationName
+	}
+
+	ctx, span := Tracer().Start(ctx, name, trace.WithAttributes(
+		attribute.String("graphql.operation.type", kind),
+		attribute.String("graphql.operation.name", operationName),
+	))
+
+	respond := next(ctx)
+	var once sync.Once
+
+	return func(ctx context.Context) *graphql.Response {
+		response := respond(ctx)
+
+		once.Do(func() {
+			var err error
+			if response != nil && len(response.Errors) > 0 {
+				err = errors.New(response.Errors.Error())
+			}
+
+			End(span, err)
+		})
+
+		return response
+	}
+}
+
+func (GraphQL) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	field := graphql.GetFieldContext(ctx)
+	if field == nil || !field.IsResolver {
+		return next(ctx)
+	}
+
+	ctx, span := Tracer().Start(ctx, field.Object+"."+field.Field.Name, trace.WithAttributes(
+		attribute.String("graphql.field.path", field.Path().String()),
+	))
+
+	result, err := next(ctx)
+	End(span, err)
+
+	return result, err
+}
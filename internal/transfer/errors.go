@@ -0,0 +1,55 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+)
+
+var (
+	ErrNegativeAmount               = errors.New("amount cannot be negative")
+	ErrInvalidAddress               = fmt.Errorf("address must be at most %d characters", MaxAddressLength)
+	ErrInsufficientBalance          = ledger.ErrInsufficientFunds
+	ErrInsufficientBalanceToReverse = errors.New("Insufficient balance to reverse transfer")
+	ErrTransferNotFound             = errors.New("transfer not found")
+	ErrTransferAlreadyReversed      = errors.New("transfer already reversed")
+	ErrCannotReverseReversal        = errors.New("cannot reverse a reversal")
+	ErrWalletNotFound               = errors.New("wallet not found")
+)
+
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrNegativeAmount, "negative_amount"},
+	{ErrInvalidAddress, "invalid_address"},
+	{ErrInsufficientBalance, "insufficient_balance"},
+	{ErrInsufficientBalanceToReverse, "insufficient_balance_to_reverse"},
+	{ErrTransferNotFound, "transfer_not_found"},
+	{ErrTransferAlreadyReversed, "transfer_already_reversed"},
+	{ErrCannotReverseReversal, "cannot_reverse_reversal"},
+	{ErrWalletNotFound, "wallet_not_found"},
+}
+
+// ErrorCode returns a short, stable name for err: the name of the error
+// above it wraps, the database code of a transaction that ran out of
+// retries, or "internal".
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			return known.code
+		}
+	}
+
+	if code, ok := db.RetryableCode(err); ok {
+		return code
+	}
+
+	return "internal"
+}
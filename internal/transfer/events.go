@@ -0,0 +1,62 @@
+package transfer
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/eventstore"
+)
+
+// transferEvents records the lifecycle of one transfer request when event
+// sourcing is enabled, and does nothing otherwise.
+type transferEvents struct {
+	enabled   bool
+	stream    string
+	requested eventstore.TransferRequested
+}
+
+func (s *service) newTransferEvents(fromAddress string, toAddress string, amount int64) *transferEvents {
+	return &transferEvents{
+		enabled:   s.options.EventSourcing,
+		stream:    eventstore.NewTransferStream(),
+		requested: eventstore.TransferRequested{From: fromAddress, To: toAddress, Amount: amount},
+	}
+}
+
+// completed appends TransferRequested and TransferCompleted inside the
+// transfer transaction. entry is nil when no tokens were moved.
+func (e *transferEvents) completed(tx Tx, entry *db.Transfer) error {
+	if !e.enabled {
+		return nil
+	}
+
+	completed := eventstore.TransferCompleted{From: e.requested.From, To: e.requested.To, Amount: e.requested.Amount}
+
+	if entry != nil {
+		completed.TransferID = entry.ID
+		completed.ReversalOf = entry.ReversalOf
+		completed.Reason = entry.Reason
+	}
+
+	return tx.AppendEvents(e.stream, e.requested, completed)
+}
+
+// rejected appends TransferRequested and TransferRejected in their own
+// transaction, because the transfer transaction has been rolled back.
+func (e *transferEvents) rejected(ctx context.Context, store Store, cause error) {
+	if !e.enabled {
+		return
+	}
+
+	rejected := eventstore.TransferRejected{
+		From:   e.requested.From,
+		To:     e.requested.To,
+		Amount: e.requested.Amount,
+		Reason: cause.Error(),
+	}
+
+	if err := store.AppendEvents(ctx, e.stream, e.requested, rejected); err != nil {
+		slog.ErrorContext(ctx, "Failed to record transfer rejection", slog.String("stream", e.stream), slog.String("error", err.Error()))
+	}
+}
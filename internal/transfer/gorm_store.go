@@ -0,0 +1,317 @@
+package transfer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/audit"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/eventstore"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"github.com/dominika232323/token-transfer-api/internal/metrics"
+	"github.com/dominika232323/token-transfer-api/internal/webhook"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Locking is how a GormStore guards wallets against concurrent transfers.
+type Locking string
+
+const (
+	// LockingPessimistic locks wallets with SELECT ... FOR UPDATE until the
+	// transaction ends.
+	LockingPessimistic Locking = "pessimistic"
+
+	// LockingOptimistic reads wallets without locking them and updates them
+	// only if their version has not changed since, retrying the transaction
+	// when it has.
+	LockingOptimistic Locking = "optimistic"
+)
+
+type StoreOptions struct {
+	// Locking defaults to LockingPessimistic.
+	Locking Locking
+
+	// Isolation is the isolation level of transfer transactions. The zero
+	// value is the database default.
+	Isolation sql.IsolationLevel
+}
+
+// GormStore keeps wallets and transfers in the database, posting every
+// transfer to the journal, the audit chain and the webhook outbox.
+type GormStore struct {
+	DB      *gorm.DB
+	options StoreOptions
+}
+
+func NewGormStore(database *gorm.DB, options StoreOptions) *GormStore {
+	if options.Locking == "" {
+		options.Locking = LockingPessimistic
+	}
+
+	return &GormStore{DB: database, options: options}
+}
+
+// Transaction runs fn and then appends the audit records of the balances it
+// moved, so the audit lock, which every transfer takes, is held only from
+// the last statement to the commit.
+func (s *GormStore) Transaction(ctx context.Context, fn func(tx Tx) error) error {
+	return db.Transaction(s.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		gtx := &gormTx{tx: tx, locking: s.options.Locking}
+
+		if err := fn(gtx); err != nil {
+			return err
+		}
+
+		return gtx.appendAudit()
+	}, &sql.TxOptions{Isolation: s.options.Isolation})
+}
+
+func (s *GormStore) AppendEvents(ctx context.Context, stream string, events ...eventstore.Event) error {
+	return db.Transaction(s.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		return eventstore.Append(tx, stream, events...)
+	})
+}
+
+func (s *GormStore) GetWallet(ctx context.Context, address string) (*db.Wallet, error) {
+	wallet, err := ledger.FindWallet(s.DB.WithContext(ctx), address)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, fmt.Errorf("failed to find wallet %s: %w", address, err)
+	}
+
+	return wallet, nil
+}
+
+func (s *GormStore) History(ctx context.Context, address string, limit int, beforeID int64) ([]db.Transfer, error) {
+	query := s.DB.WithContext(ctx).
+		Where("(from_address = ? OR to_address = ?)", address, address).
+		Order("id DESC").
+		Limit(limit)
+
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	transfers := []db.Transfer{}
+	if err := query.Find(&transfers).Error; err != nil {
+		return nil, fmt.Errorf("failed to read transfers of %s: %w", address, err)
+	}
+
+	return transfers, nil
+}
+
+type gormTx struct {
+	tx      *gorm.DB
+	locking Locking
+
+	// moves are the balance moves whose audit records are still to be
+	// appended.
+	moves []move
+}
+
+type move struct {
+	record    *db.AuditRecord
+	sender    *db.Wallet
+	recipient *db.Wallet
+}
+
+// LockWallets only reads the wallets with LockingOptimistic. MoveBalance then
+// checks that they have not changed. Hot wallets are never locked, because
+// their shards are locked one at a time when their balance moves.
+func (t *gormTx) LockWallets(fromAddress string, toAddress string) (*db.Wallet, *db.Wallet, error) {
+	addresses := []string{fromAddress, toAddress}
+	sort.Strings(addresses)
+
+	var wallets [2]db.Wallet
+
+	started := time.Now()
+
+	for i, addr := range addresses {
+		if err := t.lockWallet(&wallets[i], addr); err != nil {
+			return nil, nil, fmt.Errorf("failed to lock wallet %s: %w", addr, err)
+		}
+	}
+
+	metrics.LockWait.WithLabelValues("wallet").Observe(time.Since(started).Seconds())
+
+	if wallets[0].Address == fromAddress {
+		return &wallets[0], &wallets[1], nil
+	}
+
+	return &wallets[1], &wallets[0], nil
+}
+
+func (t *gormTx) lockWallet(wallet *db.Wallet, address string) error {
+	query := t.tx
+	if t.locking == LockingPessimistic {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	result := query.Where("address = ? AND shards = 0", address).Limit(1).Find(wallet)
+	if result.Error != nil || result.RowsAffected == 1 {
+		return result.Error
+	}
+
+	var shards int
+	if err := t.tx.Model(&db.Wallet{}).Select("shards").Where("address = ?", address).Scan(&shards).Error; err != nil {
+		return err
+	}
+
+	if shards == 0 {
+		if err := ledger.CreateWallet(t.tx, address); err != nil {
+			return err
+		}
+
+		return query.Where("address = ?", address).Take(wallet).Error
+	}
+
+	hot, err := ledger.FindWallet(t.tx, address)
+	if err != nil {
+		return err
+	}
+
+	*wallet = *hot
+	return nil
+}
+
+func (t *gormTx) LockTransfer(id int64) (*db.Transfer, error) {
+	var transfer db.Transfer
+
+	started := time.Now()
+
+	if err := t.tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&transfer, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransferNotFound
+		}
+		return nil, fmt.Errorf("failed to lock transfer %d: %w", id, err)
+	}
+
+	metrics.LockWait.WithLabelValues("transfer").Observe(time.Since(started).Seconds())
+
+	return &transfer, nil
+}
+
+func (t *gormTx) IsReversed(id int64) (bool, error) {
+	var reversed int64
+	if err := t.tx.Model(&db.Transfer{}).Where("reversal_of = ?", id).Count(&reversed).Error; err != nil {
+		return false, fmt.Errorf("failed to check for existing reversal: %w", err)
+	}
+
+	return reversed > 0, nil
+}
+
+// MoveBalance records the ledger entry, posts it to the double-entry journal
+// and queues its webhook notification. Its audit record is appended in the
+// same transaction when the function passed to Transaction returns. With
+// LockingOptimistic it returns db.ErrVersionConflict if the sender changed
+// since LockWallets read it.
+func (t *gormTx) MoveBalance(sender *db.Wallet, recipient *db.Wallet, entry *db.Transfer, allowNegative bool) error {
+	if err := t.tx.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to record transfer: %w", err)
+	}
+
+	kind, operation := ledger.KindTransfer, audit.OperationTransfer
+	if entry.ReversalOf != nil {
+		kind, operation = ledger.KindReversal, audit.OperationReversal
+	}
+
+	legs := []ledger.Leg{
+		{Account: sender.Address, Amount: -entry.Amount, AllowNegative: allowNegative},
+		{Account: recipient.Address, Amount: entry.Amount},
+	}
+
+	var err error
+	if t.locking == LockingOptimistic {
+		_, err = ledger.PostVersioned(t.tx, kind, &entry.ID, []*db.Wallet{sender, recipient}, legs...)
+	} else {
+		_, err = ledger.Post(t.tx, kind, &entry.ID, legs...)
+		if err == nil {
+			sender.Balance -= entry.Amount
+			sender.Version++
+			recipient.Balance += entry.Amount
+			recipient.Version++
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := webhook.EnqueueTransfer(t.tx, entry); err != nil {
+		return err
+	}
+
+	t.moves = append(t.moves, move{
+		record: &db.AuditRecord{
+			Operation:   operation,
+			TransferID:  &entry.ID,
+			FromAddress: sender.Address,
+			ToAddress:   recipient.Address,
+			Amount:      entry.Amount,
+		},
+		sender:    sender,
+		recipient: recipient,
+	})
+
+	return nil
+}
+
+// appendAudit appends the audit records of the balance moves. It takes the
+// audit lock before reading the balances of hot wallets, which transactions
+// that do not lock them may have changed since, so the records hold the
+// balances in the order of the chain.
+func (t *gormTx) appendAudit() error {
+	if len(t.moves) == 0 {
+		return nil
+	}
+
+	if err := audit.Lock(t.tx); err != nil {
+		return err
+	}
+
+	for _, move := range t.moves {
+		sender, err := t.auditBalance(move.sender)
+		if err != nil {
+			return err
+		}
+
+		recipient, err := t.auditBalance(move.recipient)
+		if err != nil {
+			return err
+		}
+
+		move.record.FromBalance = sender
+		move.record.ToBalance = recipient
+
+		if err := audit.Append(t.tx, move.record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// auditBalance returns the balance of wallet, reread if it is hot.
+func (t *gormTx) auditBalance(wallet *db.Wallet) (int64, error) {
+	if wallet.Shards == 0 {
+		return wallet.Balance, nil
+	}
+
+	fresh, err := ledger.FindWallet(t.tx, wallet.Address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read wallet %s: %w", wallet.Address, err)
+	}
+
+	return fresh.Balance, nil
+}
+
+func (t *gormTx) AppendEvents(stream string, events ...eventstore.Event) error {
+	return eventstore.Append(t.tx, stream, events...)
+}
@@ -0,0 +1,275 @@
+package transfer
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/eventstore"
+)
+
+// MemoryStore keeps wallets and transfers in memory, for tests that should
+// not need a database. It takes the same locks as GormStore, using a mutex
+// per wallet address and per transfer, and only publishes the changes of a
+// transaction when it commits.
+type MemoryStore struct {
+	// mu guards everything below; row locks are never acquired while it is
+	// held.
+	mu             sync.Mutex
+	locks          map[string]*sync.Mutex
+	wallets        map[string]db.Wallet
+	transfers      []db.Transfer
+	events         []memoryEvent
+	lastWalletID   int64
+	lastTransferID int64
+}
+
+type memoryEvent struct {
+	stream string
+	event  eventstore.Event
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		locks:   map[string]*sync.Mutex{},
+		wallets: map[string]db.Wallet{},
+	}
+}
+
+// Issue credits amount to address, creating the wallet if needed.
+func (s *MemoryStore) Issue(address string, amount int64) {
+	lock := s.lockFor("wallet:" + address)
+	lock.Lock()
+	defer lock.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wallet, ok := s.wallets[address]
+	if !ok {
+		s.lastWalletID++
+		wallet = db.Wallet{ID: s.lastWalletID, Address: address}
+	}
+
+	wallet.Balance += amount
+	s.wallets[address] = wallet
+}
+
+// EventCount returns the number of events appended to stream, or to all
+// streams when stream is empty.
+func (s *MemoryStore) EventCount(stream string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, appended := range s.events {
+		if stream == "" || appended.stream == stream {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (s *MemoryStore) Transaction(ctx context.Context, fn func(tx Tx) error) error {
+	tx := &memoryTx{
+		store:   s,
+		held:    map[string]*sync.Mutex{},
+		wallets: map[string]*db.Wallet{},
+	}
+	defer tx.unlock()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx.commit()
+	return nil
+}
+
+func (s *MemoryStore) AppendEvents(ctx context.Context, stream string, events ...eventstore.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, event := range events {
+		s.events = append(s.events, memoryEvent{stream: stream, event: event})
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) GetWallet(ctx context.Context, address string) (*db.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wallet, ok := s.wallets[address]
+	if !ok {
+		return nil, ErrWalletNotFound
+	}
+
+	return &wallet, nil
+}
+
+func (s *MemoryStore) History(ctx context.Context, address string, limit int, beforeID int64) ([]db.Transfer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transfers := []db.Transfer{}
+
+	for i := len(s.transfers) - 1; i >= 0 && len(transfers) < limit; i-- {
+		transfer := s.transfers[i]
+
+		if beforeID > 0 && transfer.ID >= beforeID {
+			continue
+		}
+
+		if transfer.FromAddress == address || transfer.ToAddress == address {
+			transfers = append(transfers, transfer)
+		}
+	}
+
+	return transfers, nil
+}
+
+func (s *MemoryStore) lockFor(key string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, ok := s.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.locks[key] = lock
+	}
+
+	return lock
+}
+
+// memoryTx stages wallet and transfer changes until commit, holding the row
+// locks it took until the transaction ends.
+type memoryTx struct {
+	store     *MemoryStore
+	held      map[string]*sync.Mutex
+	wallets   map[string]*db.Wallet
+	transfers []db.Transfer
+	events    []memoryEvent
+}
+
+func (t *memoryTx) lock(key string) {
+	if _, ok := t.held[key]; ok {
+		return
+	}
+
+	lock := t.store.lockFor(key)
+	lock.Lock()
+	t.held[key] = lock
+}
+
+func (t *memoryTx) unlock() {
+	for _, lock := range t.held {
+		lock.Unlock()
+	}
+}
+
+func (t *memoryTx) LockWallets(fromAddress string, toAddress string) (*db.Wallet, *db.Wallet, error) {
+	addresses := []string{fromAddress, toAddress}
+	sort.Strings(addresses)
+
+	for _, addr := range addresses {
+		t.lock("wallet:" + addr)
+	}
+
+	return t.wallet(fromAddress), t.wallet(toAddress), nil
+}
+
+// wallet returns the staged copy of a locked wallet, creating it if it does
+// not exist yet.
+func (t *memoryTx) wallet(address string) *db.Wallet {
+	if wallet, ok := t.wallets[address]; ok {
+		return wallet
+	}
+
+	t.store.mu.Lock()
+	wallet, ok := t.store.wallets[address]
+	if !ok {
+		t.store.lastWalletID++
+		wallet = db.Wallet{ID: t.store.lastWalletID, Address: address}
+	}
+	t.store.mu.Unlock()
+
+	t.wallets[address] = &wallet
+	return &wallet
+}
+
+func (t *memoryTx) LockTransfer(id int64) (*db.Transfer, error) {
+	t.lock("transfer:" + strconv.FormatInt(id, 10))
+
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	for _, transfer := range t.store.transfers {
+		if transfer.ID == id {
+			return &transfer, nil
+		}
+	}
+
+	return nil, ErrTransferNotFound
+}
+
+func (t *memoryTx) IsReversed(id int64) (bool, error) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	for _, transfer := range t.store.transfers {
+		if transfer.ReversalOf != nil && *transfer.ReversalOf == id {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (t *memoryTx) MoveBalance(sender *db.Wallet, recipient *db.Wallet, entry *db.Transfer, allowNegative bool) error {
+	t.store.mu.Lock()
+	t.store.lastTransferID++
+	entry.ID = t.store.lastTransferID
+	t.store.mu.Unlock()
+
+	entry.CreatedAt = time.Now()
+
+	sender.Balance -= entry.Amount
+	recipient.Balance += entry.Amount
+
+	t.transfers = append(t.transfers, *entry)
+	return nil
+}
+
+func (t *memoryTx) AppendEvents(stream string, events ...eventstore.Event) error {
+	for _, event := range events {
+		t.events = append(t.events, memoryEvent{stream: stream, event: event})
+	}
+
+	return nil
+}
+
+func (t *memoryTx) commit() {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	for address, wallet := range t.wallets {
+		t.store.wallets[address] = *wallet
+	}
+
+	t.store.transfers = append(t.store.transfers, t.transfers...)
+	sort.Slice(t.store.transfers, func(i, j int) bool {
+		return t.store.transfers[i].ID < t.store.transfers[j].ID
+	})
+
+	t.store.events = append(t.store.events, t.events...)
+}
@@ -0,0 +1,241 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/metrics"
+	"github.com/dominika232323/token-transfer-api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 500
+
+	// MaxAddressLength is the size of the address columns.
+	MaxAddressLength = 42
+)
+
+// Service holds the transfer business logic shared by the GraphQL and REST
+// APIs.
+type Service interface {
+	// Transfer moves amount from fromAddress to toAddress, creating the
+	// recipient wallet if it does not exist yet.
+	Transfer(ctx context.Context, fromAddress string, toAddress string, amount int64) (*Result, error)
+
+	// Reverse creates a compensating transfer for the transfer with the given
+	// id. Unless allowNegative is set it fails if the original recipient no
+	// longer holds the amount.
+	Reverse(ctx context.Context, transferID int64, reason string, allowNegative bool) (*db.Transfer, error)
+
+	GetWallet(ctx context.Context, address string) (*db.Wallet, error)
+
+	// History returns the transfers sent or received by address, newest
+	// first. Pass the id of the last transfer of the previous page as
+	// beforeID to page further back, or 0 to start from the newest.
+	History(ctx context.Context, address string, limit int, beforeID int64) ([]db.Transfer, error)
+}
+
+type Options struct {
+	// EventSourcing makes transfers append their lifecycle events to the
+	// event store.
+	EventSourcing bool
+}
+
+type Result struct {
+	// Transfer is nil when no tokens were moved (a transfer to self).
+	Transfer      *db.Transfer
+	SenderBalance int64
+}
+
+type service struct {
+	store   Store
+	options Options
+}
+
+func NewService(store Store, options Options) Service {
+	return &service{store: store, options: options}
+}
+
+func (s *service) Transfer(ctx context.Context, fromAddress string, toAddress string, amount int64) (*Result, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "Transfer", trace.WithAttributes(
+		attribute.String("transfer.from_address", fromAddress),
+		attribute.String("transfer.to_address", toAddress),
+		attribute.Int64("transfer.amount", amount),
+	))
+
+	result, err := s.transfer(ctx, fromAddress, toAddress, amount)
+
+	countTransfer("transfer", err)
+	tracing.End(span, err)
+
+	return result, err
+}
+
+func (s *service) transfer(ctx context.Context, fromAddress string, toAddress string, amount int64) (*Result, error) {
+	if err := ValidateAddresses(fromAddress, toAddress); err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+
+	events := s.newTransferEvents(fromAddress, toAddress, amount)
+
+	if amount < 0 {
+		events.rejected(ctx, s.store, ErrNegativeAmount)
+		return nil, ErrNegativeAmount
+	}
+
+	err := s.store.Transaction(ctx, func(tx Tx) error {
+		sender, recipient, err := tx.LockWallets(fromAddress, toAddress)
+		if err != nil {
+			return err
+		}
+
+		if sender.Balance < amount {
+			return ErrInsufficientBalance
+		}
+
+		if fromAddress == toAddress {
+			result.SenderBalance = sender.Balance
+			return events.completed(tx, nil)
+		}
+
+		entry := &db.Transfer{FromAddress: fromAddress, ToAddress: toAddress, Amount: amount}
+
+		if err := tx.MoveBalance(sender, recipient, entry, false); err != nil {
+			return err
+		}
+
+		result.Transfer = entry
+		result.SenderBalance = sender.Balance
+		return events.completed(tx, entry)
+	})
+
+	if err != nil {
+		events.rejected(ctx, s.store, err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *service) Reverse(ctx context.Context, transferID int64, reason string, allowNegative bool) (*db.Transfer, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "Reverse", trace.WithAttributes(
+		attribute.Int64("transfer.id", transferID),
+	))
+
+	reversal, err := s.reverse(ctx, transferID, reason, allowNegative)
+
+	countTransfer("reversal", err)
+	tracing.End(span, err)
+
+	return reversal, err
+}
+
+func (s *service) reverse(ctx context.Context, transferID int64, reason string, allowNegative bool) (*db.Transfer, error) {
+	var reversal *db.Transfer
+	var events *transferEvents
+
+	err := s.store.Transaction(ctx, func(tx Tx) error {
+		original, err := tx.LockTransfer(transferID)
+		if err != nil {
+			return err
+		}
+
+		if original.ReversalOf != nil {
+			return ErrCannotReverseReversal
+		}
+
+		reversed, err := tx.IsReversed(original.ID)
+		if err != nil {
+			return err
+		}
+
+		if reversed {
+			return ErrTransferAlreadyReversed
+		}
+
+		events = s.newTransferEvents(original.ToAddress, original.FromAddress, original.Amount)
+
+		sender, recipient, err := tx.LockWallets(original.ToAddress, original.FromAddress)
+		if err != nil {
+			return err
+		}
+
+		if sender.Balance < original.Amount && !allowNegative {
+			return ErrInsufficientBalanceToReverse
+		}
+
+		reversal = &db.Transfer{
+			FromAddress: original.ToAddress,
+			ToAddress:   original.FromAddress,
+			Amount:      original.Amount,
+			ReversalOf:  &original.ID,
+			Reason:      reason,
+		}
+
+		if err := tx.MoveBalance(sender, recipient, reversal, allowNegative); err != nil {
+			return err
+		}
+
+		return events.completed(tx, reversal)
+	})
+
+	if err != nil {
+		if events != nil {
+			events.rejected(ctx, s.store, err)
+		}
+		return nil, err
+	}
+
+	return reversal, nil
+}
+
+func (s *service) GetWallet(ctx context.Context, address string) (*db.Wallet, error) {
+	if err := ValidateAddresses(address); err != nil {
+		return nil, err
+	}
+
+	return s.store.GetWallet(ctx, address)
+}
+
+func (s *service) History(ctx context.Context, address string, limit int, beforeID int64) ([]db.Transfer, error) {
+	if limit <= 0 || limit > MaxHistoryLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", MaxHistoryLimit)
+	}
+
+	if err := ValidateAddresses(address); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.store.GetWallet(ctx, address); err != nil {
+		return nil, err
+	}
+
+	return s.store.History(ctx, address, limit, beforeID)
+}
+
+// ValidateAddresses returns ErrInvalidAddress if any of addresses does not
+// fit the address columns.
+func ValidateAddresses(addresses ...string) error {
+	for _, address := range addresses {
+		if len(address) > MaxAddressLength {
+			return ErrInvalidAddress
+		}
+	}
+
+	return nil
+}
+
+func countTransfer(operation string, err error) {
+	outcome := "completed"
+	if err != nil {
+		outcome = "failed"
+	}
+
+	metrics.Transfers.WithLabelValues(operation, outcome, ErrorCode(err)).Inc()
+}
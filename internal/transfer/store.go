@@ -0,0 +1,48 @@
+package transfer
+
+import (
+	"context"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/eventstore"
+)
+
+// Store persists the wallets and transfers a Service works on.
+type Store interface {
+	// Transaction runs fn in a transaction that commits when fn returns nil
+	// and rolls back otherwise. Locks taken through tx are held until then.
+	Transaction(ctx context.Context, fn func(tx Tx) error) error
+
+	// AppendEvents appends events to stream in a transaction of its own.
+	AppendEvents(ctx context.Context, stream string, events ...eventstore.Event) error
+
+	// GetWallet returns ErrWalletNotFound if address has no wallet.
+	GetWallet(ctx context.Context, address string) (*db.Wallet, error)
+
+	// History returns up to limit transfers sent or received by address,
+	// newest first, with ids below beforeID unless it is 0.
+	History(ctx context.Context, address string, limit int, beforeID int64) ([]db.Transfer, error)
+}
+
+// Tx is the view of a Store inside Store.Transaction.
+type Tx interface {
+	// LockWallets locks both wallets, creating missing ones with a zero
+	// balance. Wallets are locked in address order so that concurrent
+	// transfers between the same pair can never deadlock. A store with
+	// optimistic locking only reads them here, and fails MoveBalance if they
+	// changed in the meantime.
+	LockWallets(fromAddress string, toAddress string) (sender *db.Wallet, recipient *db.Wallet, err error)
+
+	// LockTransfer returns ErrTransferNotFound if there is no transfer with id.
+	LockTransfer(id int64) (*db.Transfer, error)
+
+	// IsReversed reports whether the transfer with id has been reversed.
+	IsReversed(id int64) (bool, error)
+
+	// MoveBalance records entry and moves its amount from sender to
+	// recipient, updating both wallets in place. allowNegative lets the
+	// sender's balance go below zero.
+	MoveBalance(sender *db.Wallet, recipient *db.Wallet, entry *db.Transfer, allowNegative bool) error
+
+	AppendEvents(stream string, events ...eventstore.Event) error
+}
@@ -0,0 +1,336 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// Dispatcher fans outbox messages out to every active endpoint and delivers
+// them, retrying failures with exponential backoff until MaxAttempts is
+// reached, after which the delivery is dead-lettered.
+type Dispatcher struct {
+	DB           *gorm.DB
+	Client       *http.Client
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	PollInterval time.Duration
+
+	// ClaimTimeout is how long a claimed delivery is skipped by other
+	// dispatchers while it is being sent. It must exceed the client timeout.
+	ClaimTimeout time.Duration
+}
+
+func NewDispatcher(database *gorm.DB) *Dispatcher {
+	return &Dispatcher{
+		DB:           database,
+		Client:       &http.Client{Timeout: 10 * time.Second},
+		BatchSize:    100,
+		MaxAttempts:  8,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   time.Hour,
+		PollInterval: time.Second,
+		ClaimTimeout: 5 * time.Minute,
+	}
+}
+
+// Run runs the dispatcher every PollInterval. It returns when ctx is
+// cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.RunOnce(ctx); err != nil {
+				slog.ErrorContext(ctx, "Webhook dispatch failed", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// RunOnce fans out pending outbox messages and attempts every delivery that
+// is due.
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	if err := d.fanOut(ctx); err != nil {
+		return err
+	}
+
+	return d.deliverDue(ctx)
+}
+
+func (d *Dispatcher) fanOut(ctx context.Context) error {
+	return db.Transaction(d.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		var messages []db.OutboxMessage
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("dispatched_at IS NULL").
+			Order("id").
+			Limit(d.BatchSize).
+			Find(&messages).Error
+		if err != nil {
+			return fmt.Errorf("failed to read outbox: %w", err)
+		}
+
+		if len(messages) == 0 {
+			return nil
+		}
+
+		var endpoints []db.WebhookEndpoint
+		if err := tx.Where("active = ?", true).Find(&endpoints).Error; err != nil {
+			return fmt.Errorf("failed to read webhook endpoints: %w", err)
+		}
+
+		now := time.Now()
+		ids := make([]int64, 0, len(messages))
+
+		for _, message := range messages {
+			ids = append(ids, message.ID)
+
+			for _, endpoint := range endpoints {
+				delivery := db.WebhookDelivery{
+					OutboxID:      message.ID,
+					EndpointID:    endpoint.ID,
+					EventType:     message.EventType,
+					Status:        StatusPending,
+					NextAttemptAt: now,
+				}
+
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&delivery).Error; err != nil {
+					return fmt.Errorf("failed to schedule webhook delivery: %w", err)
+				}
+			}
+		}
+
+		return tx.Model(&db.OutboxMessage{}).Where("id IN ?", ids).Update("dispatched_at", now).Error
+	})
+}
+
+// claim is a due delivery claimed by one dispatcher, with what it needs to
+// send it.
+type claim struct {
+	delivery db.WebhookDelivery
+	endpoint db.WebhookEndpoint
+	payload  []byte
+}
+
+// deliverDue claims the due deliveries in a short transaction and sends them
+// outside of any, recording each attempt in a transaction of its own.
+func (d *Dispatcher) deliverDue(ctx context.Context) error {
+	claimedAt := time.Now()
+
+	claims, err := d.claimDue(ctx, claimedAt)
+	if err != nil {
+		return err
+	}
+
+	// Stop before a send could outlast the claim, so that no other dispatcher
+	// sends the same delivery meanwhile. The rest is claimed again later.
+	deadline := claimedAt.Add(d.ClaimTimeout - d.Client.Timeout)
+
+	for i := range claims {
+		if ctx.Err() != nil || time.Now().After(deadline) {
+			return nil
+		}
+
+		if err := d.attempt(ctx, &claims[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// claimDue reads the due deliveries and postpones their next attempt by
+// ClaimTimeout, so that other dispatchers skip them while they are sent.
+func (d *Dispatcher) claimDue(ctx context.Context, now time.Time) ([]claim, error) {
+	var claims []claim
+
+	err := db.Transaction(d.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		claims = nil
+
+		var deliveries []db.WebhookDelivery
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", StatusPending, now).
+			Order("next_attempt_at").
+			Limit(d.BatchSize).
+			Find(&deliveries).Error
+		if err != nil {
+			return fmt.Errorf("failed to read due webhook deliveries: %w", err)
+		}
+
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, 0, len(deliveries))
+		endpointIDs := make([]int64, 0, len(deliveries))
+		outboxIDs := make([]int64, 0, len(deliveries))
+
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+			endpointIDs = append(endpointIDs, delivery.EndpointID)
+			outboxIDs = append(outboxIDs, delivery.OutboxID)
+		}
+
+		err = tx.Model(&db.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(d.ClaimTimeout)).Error
+		if err != nil {
+			return fmt.Errorf("failed to claim webhook deliveries: %w", err)
+		}
+
+		var endpoints []db.WebhookEndpoint
+		if err := tx.Where("id IN ?", endpointIDs).Find(&endpoints).Error; err != nil {
+			return fmt.Errorf("failed to read webhook endpoints: %w", err)
+		}
+
+		var messages []db.OutboxMessage
+		if err := tx.Where("id IN ?", outboxIDs).Find(&messages).Error; err != nil {
+			return fmt.Errorf("failed to read outbox messages: %w", err)
+		}
+
+		endpointsByID := make(map[int64]db.WebhookEndpoint, len(endpoints))
+		for _, endpoint := range endpoints {
+			endpointsByID[endpoint.ID] = endpoint
+		}
+
+		payloads := make(map[int64][]byte, len(messages))
+		for _, message := range messages {
+			payloads[message.ID] = []byte(message.Payload)
+		}
+
+		for _, delivery := range deliveries {
+			endpoint, ok := endpointsByID[delivery.EndpointID]
+			if !ok {
+				return fmt.Errorf("failed to read webhook endpoint %d: %w", delivery.EndpointID, gorm.ErrRecordNotFound)
+			}
+
+			payload, ok := payloads[delivery.OutboxID]
+			if !ok {
+				return fmt.Errorf("failed to read outbox message %d: %w", delivery.OutboxID, gorm.ErrRecordNotFound)
+			}
+
+			claims = append(claims, claim{delivery: delivery, endpoint: endpoint, payload: payload})
+		}
+
+		return nil
+	})
+
+	return claims, err
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, claim *claim) error {
+	delivery := &claim.delivery
+
+	started := time.Now()
+	statusCode, sendErr := d.send(ctx, &claim.endpoint, delivery, claim.payload, started)
+
+	attempt := db.WebhookAttempt{
+		DeliveryID:  delivery.ID,
+		AttemptedAt: started,
+		StatusCode:  statusCode,
+		DurationMs:  time.Since(started).Milliseconds(),
+	}
+
+	previousAttempts := delivery.Attempts
+	delivery.Attempts++
+
+	switch {
+	case sendErr == nil:
+		delivered := time.Now()
+		delivery.Status = StatusDelivered
+		delivery.DeliveredAt = &delivered
+		delivery.LastError = ""
+	case delivery.Attempts >= d.MaxAttempts:
+		attempt.Error = sendErr.Error()
+		delivery.Status = StatusDead
+		delivery.LastError = sendErr.Error()
+	default:
+		attempt.Error = sendErr.Error()
+		delivery.LastError = sendErr.Error()
+		delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempts))
+	}
+
+	// The attempt is recorded even if the dispatcher is stopping.
+	return db.Transaction(d.DB.WithContext(context.WithoutCancel(ctx)), func(tx *gorm.DB) error {
+		if err := tx.Create(&attempt).Error; err != nil {
+			return fmt.Errorf("failed to record webhook attempt: %w", err)
+		}
+
+		// A delivery whose claim lapsed may have been attempted again since;
+		// the later attempt's outcome is kept.
+		err := tx.Model(&db.WebhookDelivery{}).
+			Where("id = ? AND attempts = ?", delivery.ID, previousAttempts).
+			Select("Attempts", "Status", "DeliveredAt", "LastError", "NextAttemptAt").
+			Updates(delivery).Error
+		if err != nil {
+			return fmt.Errorf("failed to update webhook delivery: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (d *Dispatcher) send(ctx context.Context, endpoint *db.WebhookEndpoint, delivery *db.WebhookDelivery, body []byte, now time.Time) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := now.Unix()
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Webhook-Id", strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set("X-Webhook-Event", delivery.EventType)
+	request.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	request.Header.Set("X-Webhook-Signature", Sign(endpoint, timestamp, body, now))
+
+	response, err := d.Client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("endpoint responded with status %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
+
+// backoff returns BaseBackoff * 2^(attempts-1), capped at MaxBackoff.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.BaseBackoff
+
+	for i := 1; i < attempts && delay < d.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > d.MaxBackoff {
+		return d.MaxBackoff
+	}
+
+	return delay
+}
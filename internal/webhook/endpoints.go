@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SecretGracePeriod is how long the previous secret keeps signing deliveries
+// after a rotation, giving consumers time to deploy the new one.
+const SecretGracePeriod = 24 * time.Hour
+
+var ErrEndpointNotFound = errors.New("webhook endpoint not found")
+
+// Register stores a new endpoint with a freshly generated signing secret.
+func Register(database *gorm.DB, rawURL string) (*db.WebhookEndpoint, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid webhook url %q", rawURL)
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := &db.WebhookEndpoint{URL: rawURL, Secret: secret, Active: true}
+
+	if err := database.Create(endpoint).Error; err != nil {
+		return nil, fmt.Errorf("failed to register webhook: %w", err)
+	}
+
+	return endpoint, nil
+}
+
+// RotateSecret replaces the endpoint's secret. The old secret stays valid
+// for SecretGracePeriod.
+func RotateSecret(database *gorm.DB, id int64) (*db.WebhookEndpoint, error) {
+	var endpoint db.WebhookEndpoint
+
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&endpoint, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrEndpointNotFound
+			}
+			return fmt.Errorf("failed to lock webhook endpoint %d: %w", id, err)
+		}
+
+		secret, err := newSecret()
+		if err != nil {
+			return err
+		}
+
+		expiresAt := time.Now().Add(SecretGracePeriod)
+
+		endpoint.PreviousSecret = endpoint.Secret
+		endpoint.PreviousSecretExpiresAt = &expiresAt
+		endpoint.Secret = secret
+
+		return tx.Save(&endpoint).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &endpoint, nil
+}
+
+// Sign returns the value of the X-Webhook-Signature header for body sent at
+// timestamp: one "v1=<hex hmac>" element per valid secret, comma separated.
+func Sign(endpoint *db.WebhookEndpoint, timestamp int64, body []byte, now time.Time) string {
+	signatures := []string{"v1=" + signature(endpoint.Secret, timestamp, body)}
+
+	if endpoint.PreviousSecret != "" && endpoint.PreviousSecretExpiresAt != nil && now.Before(*endpoint.PreviousSecretExpiresAt) {
+		signatures = append(signatures, "v1="+signature(endpoint.PreviousSecret, timestamp, body))
+	}
+
+	return strings.Join(signatures, ",")
+}
+
+// Verify checks a signature header produced by Sign against one secret. It is
+// what consumers are expected to implement.
+func Verify(secret string, timestamp int64, body []byte, header string) bool {
+	expected := []byte("v1=" + signature(secret, timestamp, body))
+
+	for _, provided := range strings.Split(header, ",") {
+		if hmac.Equal([]byte(strings.TrimSpace(provided)), expected) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func signature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	return "whsec_" + hex.EncodeToString(raw), nil
+}
+
+// Deliveries lists the most recent deliveries with their attempts, optionally
+// filtered by endpoint (0 for all) and status ("" for all).
+func Deliveries(database *gorm.DB, endpointID int64, status string, limit int) ([]db.WebhookDelivery, error) {
+	query := database.Preload("AttemptLog", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("attempted_at")
+	}).Order("id DESC").Limit(limit)
+
+	if endpointID != 0 {
+		query = query.Where("endpoint_id = ?", endpointID)
+	}
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []db.WebhookDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to read webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
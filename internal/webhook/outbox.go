@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"gorm.io/gorm"
+)
+
+const (
+	EventTransferCompleted = "transfer.completed"
+	EventTransferReversed  = "transfer.reversed"
+)
+
+type TransferPayload struct {
+	TransferID  int64     `json:"transfer_id"`
+	FromAddress string    `json:"from_address"`
+	ToAddress   string    `json:"to_address"`
+	Amount      int64     `json:"amount"`
+	ReversalOf  *int64    `json:"reversal_of,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// EnqueueTransfer writes the outbox message announcing transfer. It must run
+// inside the transaction that records the transfer so the notification is
+// sent if and only if the transfer commits.
+func EnqueueTransfer(tx *gorm.DB, transfer *db.Transfer) error {
+	eventType := EventTransferCompleted
+	if transfer.ReversalOf != nil {
+		eventType = EventTransferReversed
+	}
+
+	payload, err := json.Marshal(TransferPayload{
+		TransferID:  transfer.ID,
+		FromAddress: transfer.FromAddress,
+		ToAddress:   transfer.ToAddress,
+		Amount:      transfer.Amount,
+		ReversalOf:  transfer.ReversalOf,
+		Reason:      transfer.Reason,
+		CreatedAt:   transfer.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox message: %w", err)
+	}
+
+	message := db.OutboxMessage{EventType: eventType, Payload: string(payload)}
+
+	if err := tx.Create(&message).Error; err != nil {
+		return fmt.Errorf("failed to write outbox message: %w", err)
+	}
+
+	return nil
+}
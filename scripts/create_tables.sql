@@ -1,5 +0,0 @@
-CREATE TABLE IF NOT EXISTS wallets (
-    id SERIAl  PRIMARY KEY,
-    address VARCHAR(42) UNIQUE NOT NULL,
-    balance BIGINT NOT NULL
-);
\ No newline at end of file
@@ -1,4 +0,0 @@
-INSERT INTO wallets (address, balance)
-VALUES
-    ('0x0000000000000000000000000000000000000000', 1000000)
-ON CONFLICT (address) DO NOTHING;
@@ -1,48 +1,232 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/dominika232323/token-transfer-api/graph"
+	"github.com/dominika232323/token-transfer-api/internal/auth"
+	"github.com/dominika232323/token-transfer-api/internal/cli"
+	"github.com/dominika232323/token-transfer-api/internal/config"
 	"github.com/dominika232323/token-transfer-api/internal/db"
-	"log"
+	"github.com/dominika232323/token-transfer-api/internal/eventstore"
+	"github.com/dominika232323/token-transfer-api/internal/health"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"github.com/dominika232323/token-transfer-api/internal/logging"
+	"github.com/dominika232323/token-transfer-api/internal/metrics"
+	"github.com/dominika232323/token-transfer-api/internal/migrate"
+	"github.com/dominika232323/token-transfer-api/internal/queue"
+	"github.com/dominika232323/token-transfer-api/internal/reconcile"
+	"github.com/dominika232323/token-transfer-api/internal/rest"
+	"github.com/dominika232323/token-transfer-api/internal/server"
+	"github.com/dominika232323/token-transfer-api/internal/statement"
+	"github.com/dominika232323/token-transfer-api/internal/tracing"
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+	"github.com/dominika232323/token-transfer-api/internal/webhook"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
 	"github.com/99designs/gqlgen/graphql/handler/lru"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/gorilla/websocket"
 	"github.com/vektah/gqlparser/v2/ast"
+	"gorm.io/gorm"
 )
 
-const defaultPort = "8080"
-
 func main() {
-	database := db.Connect()
+	if len(os.Args) > 1 {
+		if err := cli.Run(os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	logging.Setup(cfg.Log, os.Stdout)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+
+	database, err := db.Connect(ctx, cfg.Database)
+	if err != nil {
+		fatal("Failed to connect to database", err)
+	}
+
+	if cfg.Database.HealthInterval > 0 {
+		db.StartHealthMonitor(ctx, database, cfg.Database.HealthInterval)
+	}
+
+	if err := migrate.Check(database); err != nil {
+		fatal("Refusing to start", err)
+	}
+
+	if err := startEventSourcing(database, cfg.Features.EventSourcing); err != nil {
+		fatal("Failed to set up the event store", err)
+	}
+
+	if err := ledger.ConfigureShards(database, cfg.Features.HotWallets, cfg.Features.HotWalletShards); err != nil {
+		fatal("Failed to configure hot wallets", err)
+	}
+
+	workers := health.NewWorkers()
+
+	if cfg.Features.ReconcileInterval > 0 {
+		workers.Go("reconcile", func(ctx context.Context) {
+			reconcile.RunJob(ctx, database, cfg.Features.ReconcileInterval)
+		})
+	}
+
+	if cfg.Features.CheckpointInterval > 0 {
+		workers.Go("checkpoint", func(ctx context.Context) {
+			ledger.RunCheckpointJob(ctx, database, cfg.Features.CheckpointInterval)
+		})
+	}
+
+	if cfg.Features.WebhookDispatcher {
+		dispatcher := webhook.NewDispatcher(database)
+		workers.Go("webhook", dispatcher.Run)
+	}
+
+	storeOptions := transfer.StoreOptions{
+		Locking:   transfer.Locking(cfg.Database.Locking),
+		Isolation: db.IsolationLevel(cfg.Database.Isolation),
+	}
+
+	serviceOptions := transfer.Options{
+		EventSourcing: cfg.Features.EventSourcing,
+	}
+
+	transfers := transfer.NewService(transfer.NewGormStore(database, storeOptions), serviceOptions)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = defaultPort
+	if cfg.Features.QueueWorkers > 0 {
+		pool := queue.NewPool(database, storeOptions, serviceOptions)
+		pool.Workers = cfg.Features.QueueWorkers
+		pool.BatchSize = cfg.Features.QueueBatchSize
+		pool.PollInterval = cfg.Features.QueuePollInterval
+		workers.Go("queue", pool.Run)
 	}
 
 	srv := handler.New(graph.NewExecutableSchema(graph.Config{
-		Resolvers: &graph.Resolver{DB: database},
+		Resolvers:  &graph.Resolver{DB: database, Transfers: transfers},
+		Directives: graph.NewDirectives(),
 	}))
 
 	srv.AddTransport(transport.Options{})
 	srv.AddTransport(transport.GET{})
 	srv.AddTransport(transport.POST{})
+	cors := server.NewCORS(cfg.Server.CORSOrigins)
 
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		Upgrader:              websocket.Upgrader{CheckOrigin: cors.CheckOrigin},
+	})
+
+	srv.SetErrorPresenter(logging.ErrorPresenter)
+	srv.SetRecoverFunc(server.RecoverResolver)
 	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
 
 	srv.Use(extension.Introspection{})
+	srv.Use(metrics.GraphQL{})
+	srv.Use(tracing.GraphQL{})
 	srv.Use(extension.AutomaticPersistedQuery{
 		Cache: lru.New[string](100),
 	})
 
-	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", srv)
+	if cfg.Features.Playground {
+		http.Handle("/", playground.Handler("GraphQL playground", "/query"))
+	}
+
+	if cfg.Features.RESTAPI {
+		http.Handle("/v1/", tracing.Middleware(rest.NewHandler(transfers)))
+	}
+
+	if cfg.Features.Metrics {
+		sqlDB, err := database.DB()
+		if err != nil {
+			fatal("Failed to get raw DB", err)
+		}
+
+		if err := metrics.RegisterDatabase(sqlDB); err != nil {
+			fatal("Failed to register database metrics", err)
+		}
+
+		http.Handle("GET /metrics", metrics.Handler())
+	}
+
+	http.Handle("GET /healthz", health.LivenessHandler())
+	http.Handle("GET /readyz", health.ReadinessHandler(health.NewChecker(database, workers)))
+	http.Handle("GET /statements/{address}", tracing.Middleware(statement.Handler(database)))
+	http.Handle("/query", server.LimitBody(int64(cfg.Server.MaxBodyBytes),
+		tracing.Middleware(auth.AdminMiddleware(cfg.AdminToken, srv))))
+
+	port := strconv.Itoa(cfg.Port)
+
+	if cfg.Features.Playground {
+		slog.Info("Connect to http://localhost:" + port + "/ for GraphQL playground")
+	} else {
+		slog.Info("Listening", slog.String("port", port))
+	}
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		fatal("Failed to listen", err)
+	}
+
+	handler := logging.Middleware(server.Recover(cors.Middleware(http.DefaultServeMux)))
+	api := server.New(handler, workers, database, cfg.Server)
+	runErr := api.Run(ctx, listener)
+
+	tracingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := shutdownTracing(tracingCtx); err != nil {
+		slog.Error("Failed to flush traces", slog.String("error", err.Error()))
+	}
+
+	if runErr != nil {
+		fatal("Server stopped", runErr)
+	}
+
+	slog.Info("Shut down")
+}
+
+// startEventSourcing bootstraps the event store from the current balances
+// when event sourcing is enabled on a database whose events are not complete,
+// and records that it is suspended when it is disabled.
+func startEventSourcing(database *gorm.DB, enabled bool) error {
+	if !enabled {
+		_, err := eventstore.Suspend(database)
+		return err
+	}
+
+	bootstrapped, err := eventstore.Bootstrap(database)
+	if bootstrapped {
+		slog.Info("Bootstrapped the event store from the current balances")
+	}
+
+	return err
+}
 
-	log.Printf("connect to http://localhost:%s/ for GraphQL playground", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+func fatal(message string, err error) {
+	slog.Error(message, slog.String("error", err.Error()))
+	os.Exit(1)
 }
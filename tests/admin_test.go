@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminMiddleware(t *testing.T) {
+	var served, admin bool
+	handler := auth.AdminMiddleware("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = true
+		admin = auth.IsAdmin(r.Context())
+	}))
+
+	for _, test := range []struct {
+		name   string
+		header string
+		status int
+		served bool
+		admin  bool
+	}{
+		{name: "no header", status: http.StatusOK, served: true},
+		{name: "bearer token", header: "Bearer secret", status: http.StatusOK, served: true, admin: true},
+		{name: "wrong token", header: "Bearer guess", status: http.StatusOK, served: true},
+		{name: "bare token", header: "secret", status: http.StatusUnauthorized},
+		{name: "other scheme", header: "Basic secret", status: http.StatusUnauthorized},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			served, admin = false, false
+
+			request := httptest.NewRequest(http.MethodPost, "/query", nil)
+			if test.header != "" {
+				request.Header.Set("Authorization", test.header)
+			}
+
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, request)
+
+			assert.Equal(t, test.status, response.Code)
+			assert.Equal(t, test.served, served)
+			assert.Equal(t, test.admin, admin)
+		})
+	}
+}
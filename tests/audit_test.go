@@ -0,0 +1,90 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/audit"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditChainRecordsEveryBalanceChange(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+
+	_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	_, err = mutation.ReverseTransfer(context.Background(), strconv.FormatInt(LastTransfer(t).ID, 10), "refund", nil)
+	assert.NoError(t, err)
+
+	var records []db.AuditRecord
+	testDB.Where("transfer_id IS NOT NULL").Order("id").Find(&records)
+
+	assert.Len(t, records, 2)
+	assert.Equal(t, audit.OperationTransfer, records[0].Operation)
+	assert.Equal(t, int64(800), records[0].FromBalance)
+	assert.Equal(t, int64(300), records[0].ToBalance)
+	assert.Equal(t, audit.OperationReversal, records[1].Operation)
+	assert.Equal(t, records[0].Hash, records[1].PrevHash)
+
+	report, err := audit.Verify(testDB, 0, 0)
+	assert.NoError(t, err)
+	assert.True(t, report.Valid)
+	assert.Equal(t, int64(4), report.Checked)
+}
+
+func TestAuditChainDetectsForgedRecord(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+
+	_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	forged := db.AuditRecord{
+		Operation:   audit.OperationTransfer,
+		FromAddress: recipientAddress,
+		ToAddress:   senderAddress,
+		Amount:      300,
+		PrevHash:    "forged",
+	}
+	forged.Hash = audit.Hash(&forged)
+	assert.NoError(t, testDB.Create(&forged).Error)
+
+	_, err = mutation.Transfer(context.Background(), senderAddress, recipientAddress, 100)
+	assert.NoError(t, err)
+
+	report, err := audit.Verify(testDB, 0, 0)
+	assert.NoError(t, err)
+	assert.False(t, report.Valid)
+	assert.Equal(t, int64(3), report.Checked)
+	assert.Equal(t, forged.ID, *report.FirstBrokenID)
+
+	report, err = audit.Verify(testDB, 1, 1)
+	assert.NoError(t, err)
+	assert.True(t, report.Valid)
+}
+
+func TestAuditLogIsAppendOnly(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+
+	_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	err = testDB.Exec("UPDATE audit_log SET amount = 1").Error
+	assert.Error(t, err)
+
+	err = testDB.Exec("DELETE FROM audit_log").Error
+	assert.Error(t, err)
+}
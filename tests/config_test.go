@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFromEnvironment(t *testing.T) {
+	ClearConfigEnv(t)
+	t.Setenv("POSTGRES_HOST", "postgres.internal")
+	t.Setenv("POSTGRES_USER", "api")
+	t.Setenv("POSTGRES_PASSWORD", "s3cret pass")
+	t.Setenv("POSTGRES_DB", "tokens")
+	t.Setenv("POSTGRES_SSLMODE", "verify-full")
+	t.Setenv("DB_MAX_OPEN_CONNS", "10")
+	t.Setenv("DB_STATEMENT_TIMEOUT", "2s")
+	t.Setenv("EVENT_SOURCING", "true")
+	t.Setenv("HOT_WALLETS", "0x01, 0x02,")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+
+	cfg, err := config.FromEnv("")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 8080, cfg.Port)
+		assert.Equal(t, 10, cfg.Database.MaxOpenConns)
+		assert.True(t, cfg.Features.EventSourcing)
+		assert.Equal(t, []string{"0x01", "0x02"}, cfg.Features.HotWallets)
+		assert.Equal(t, 0.25, cfg.Tracing.SampleRatio)
+		assert.Equal(t,
+			"host=postgres.internal port=5432 user=api password='s3cret pass' dbname=tokens sslmode=verify-full "+
+				"connect_timeout=5 statement_timeout=2000",
+			cfg.Database.PostgresDSN())
+	}
+}
+
+func TestConfigDSNOverride(t *testing.T) {
+	ClearConfigEnv(t)
+	t.Setenv("DATABASE_URL", "postgres://api:secret@db:5432/tokens?sslmode=require")
+
+	cfg, err := config.FromEnv("")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "postgres://api:secret@db:5432/tokens?sslmode=require", cfg.Database.PostgresDSN())
+	}
+}
+
+func TestConfigFileIsOverriddenByEnvironment(t *testing.T) {
+	ClearConfigEnv(t)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+port: 9090
+database:
+  driver: sqlite
+  sqlite_path: /var/lib/tokens.db
+features:
+  reconcile_interval: 15m
+  playground: false
+`), 0o600))
+
+	t.Setenv("PORT", "9191")
+
+	cfg, err := config.FromEnv(path)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 9191, cfg.Port)
+		assert.Equal(t, "sqlite", cfg.Database.Driver)
+		assert.Equal(t, "/var/lib/tokens.db", cfg.Database.SQLitePath)
+		assert.Equal(t, 15*time.Minute, cfg.Features.ReconcileInterval)
+		assert.False(t, cfg.Features.Playground)
+		assert.True(t, cfg.Features.RESTAPI)
+	}
+}
+
+func TestConfigFileRejectsUnknownKeys(t *testing.T) {
+	ClearConfigEnv(t)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("database:\n  hostname: db\n"), 0o600))
+
+	_, err := config.FromEnv(path)
+	assert.ErrorContains(t, err, "hostname")
+}
+
+func TestConfigValidation(t *testing.T) {
+	ClearConfigEnv(t)
+	t.Setenv("PORT", "not-a-port")
+	t.Setenv("POSTGRES_SSLMODE", "sometimes")
+	t.Setenv("DB_MAX_OPEN_CONNS", "2")
+	t.Setenv("DB_MAX_IDLE_CONNS", "5")
+	t.Setenv("LOG_LEVEL", "verbose")
+	t.Setenv("LOG_FORMAT", "xml")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "-1s")
+	t.Setenv("HTTP_MAX_BODY_BYTES", "0")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://wallet.example.com,wallet.example.com,https://x.example.com/path")
+
+	_, err := config.FromEnv("")
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `PORT: invalid integer "not-a-port"`)
+		assert.Contains(t, err.Error(), "POSTGRES_USER is required")
+		assert.Contains(t, err.Error(), "POSTGRES_SSLMODE must be")
+		assert.Contains(t, err.Error(), "DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS")
+		assert.Contains(t, err.Error(), `LOG_LEVEL must be debug, info, warn or error, got "verbose"`)
+		assert.Contains(t, err.Error(), `LOG_FORMAT must be json or text, got "xml"`)
+		assert.Contains(t, err.Error(), "HTTP_WRITE_TIMEOUT must not be negative")
+		assert.Contains(t, err.Error(), "HTTP_MAX_BODY_BYTES must be at least 1")
+		assert.Contains(t, err.Error(), `got "wallet.example.com"`)
+		assert.Contains(t, err.Error(), `got "https://x.example.com/path"`)
+		assert.NotContains(t, err.Error(), `got "https://wallet.example.com"`)
+	}
+
+	ClearConfigEnv(t)
+	t.Setenv("DB_DRIVER", "mysql")
+
+	_, err = config.FromEnv("")
+	assert.ErrorContains(t, err, `DB_DRIVER must be postgres or sqlite, got "mysql"`)
+}
+
+func TestConfigRedactsSecrets(t *testing.T) {
+	ClearConfigEnv(t)
+	t.Setenv("POSTGRES_USER", "api")
+	t.Setenv("POSTGRES_PASSWORD", "hunter2")
+	t.Setenv("POSTGRES_DB", "tokens")
+	t.Setenv("ADMIN_TOKEN", "admin-token")
+
+	cfg, err := config.FromEnv("")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	content, err := cfg.Redacted().YAML()
+	assert.NoError(t, err)
+
+	assert.NotContains(t, string(content), "hunter2")
+	assert.NotContains(t, string(content), "admin-token")
+	assert.Contains(t, string(content), "password: REDACTED")
+	assert.Contains(t, string(content), "user: api")
+	assert.Contains(t, string(content), `dsn: ""`, "unset secrets should stay empty")
+
+	assert.Equal(t, "hunter2", cfg.Database.Password, "redacting must not modify the original")
+}
+
+// ClearConfigEnv unsets every setting for the duration of the test, so that
+// the developer's environment cannot leak into it.
+func ClearConfigEnv(t *testing.T) {
+	for _, name := range []string{
+		"PORT", "ADMIN_TOKEN", "DB_DRIVER", "DATABASE_URL", "POSTGRES_HOST", "POSTGRES_PORT", "POSTGRES_USER",
+		"POSTGRES_PASSWORD", "POSTGRES_DB", "POSTGRES_SSLMODE", "POSTGRES_SSLROOTCERT", "POSTGRES_SSLCERT",
+		"POSTGRES_SSLKEY", "SQLITE_PATH", "DB_MAX_OPEN_CONNS", "DB_MAX_IDLE_CONNS", "DB_CONN_MAX_LIFETIME",
+		"DB_CONN_MAX_IDLE_TIME", "DB_CONNECT_TIMEOUT", "DB_STARTUP_TIMEOUT", "DB_LOCKING", "DB_ISOLATION",
+		"DB_TX_MAX_RETRIES", "DB_HEALTH_INTERVAL", "DB_STATEMENT_TIMEOUT", "EVENT_SOURCING", "REST_API", "PLAYGROUND",
+		"METRICS", "WEBHOOK_DISPATCHER", "RECONCILE_INTERVAL", "CHECKPOINT_INTERVAL", "HOT_WALLETS", "HOT_WALLET_SHARDS",
+		"QUEUE_WORKERS", "QUEUE_BATCH_SIZE", "QUEUE_POLL_INTERVAL", "TRACING_EXPORTER", "TRACING_FILE",
+		"TRACING_OTLP_ENDPOINT", "TRACING_SAMPLE_RATIO", "OTEL_SERVICE_NAME", "DB_SLOW_QUERY_THRESHOLD", "LOG_LEVEL",
+		"LOG_FORMAT", "SHUTDOWN_TIMEOUT", "HTTP_READ_HEADER_TIMEOUT", "HTTP_READ_TIMEOUT", "HTTP_WRITE_TIMEOUT",
+		"HTTP_IDLE_TIMEOUT", "HTTP_MAX_BODY_BYTES", "CORS_ALLOWED_ORIGINS",
+	} {
+		t.Setenv(name, "")
+	}
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/config"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectConfiguresPool(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Driver = db.DriverSQLite
+	cfg.SQLitePath = filepath.Join(t.TempDir(), "pool.db")
+	cfg.MaxOpenConns = 7
+
+	database, err := db.Connect(context.Background(), cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	sqlDB, err := database.DB()
+	assert.NoError(t, err)
+	defer sqlDB.Close()
+
+	assert.Equal(t, 7, sqlDB.Stats().MaxOpenConnections)
+}
+
+func TestConnectRetriesUntilStartupTimeout(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Driver = db.DriverSQLite
+	cfg.SQLitePath = filepath.Join(t.TempDir(), "missing", "unreachable.db")
+	cfg.StartupTimeout = 2 * time.Second
+
+	started := time.Now()
+	_, err := db.Connect(context.Background(), cfg)
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed to connect to database after")
+		assert.NotContains(t, err.Error(), "after 1 attempts", "the first failure should be retried")
+	}
+
+	assert.Less(t, time.Since(started), cfg.StartupTimeout)
+}
+
+func TestConnectStopsRetryingWhenCancelled(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Driver = db.DriverSQLite
+	cfg.SQLitePath = filepath.Join(t.TempDir(), "missing", "unreachable.db")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := db.Connect(ctx, cfg)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
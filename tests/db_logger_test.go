@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/config"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerDropsParameterValues(t *testing.T) {
+	logger := db.NewLogger(0)
+
+	sql, params := logger.ParamsFilter(context.Background(), "UPDATE webhook_endpoints SET secret = ? WHERE id = ?", "whsec_1", 7)
+	assert.Equal(t, "UPDATE webhook_endpoints SET secret = ? WHERE id = ?", sql)
+	assert.Empty(t, params)
+}
+
+func TestLoggerKeepsNumberedPlaceholders(t *testing.T) {
+	output := CaptureLogs(t, config.Log{Level: "debug", Format: "json"})
+
+	// GORM renders the Postgres placeholders it has no values for as $n$.
+	db.NewLogger(0).Trace(context.Background(), time.Now(), func() (string, int64) {
+		return "UPDATE webhook_endpoints SET secret = $1$ WHERE id = $12$", 1
+	}, nil)
+
+	records := DecodeLogs(t, output)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "UPDATE webhook_endpoints SET secret = $1 WHERE id = $12", records[0]["sql"])
+	}
+}
@@ -0,0 +1,202 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/graph"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/eventstore"
+	"github.com/dominika232323/token-transfer-api/internal/migrate"
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventSourcedTransfersReplay(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	mutation := SetUpEventSourcedDatabase(t, senderAddress, 1000, recipientAddress, 100)
+
+	_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	_, err = mutation.Transfer(context.Background(), recipientAddress, senderAddress, 5000)
+	assert.Error(t, err)
+
+	_, err = mutation.ReverseTransfer(context.Background(), strconv.FormatInt(LastTransfer(t).ID, 10), "refund", nil)
+	assert.NoError(t, err)
+
+	_, err = mutation.Transfer(context.Background(), senderAddress, "0x0000000000000000000000000000000000000003", 300)
+	assert.NoError(t, err)
+
+	var types []string
+	testDB.Model(&db.Event{}).Order("id").Pluck("type", &types)
+	assert.Equal(t, []string{
+		eventstore.TypeTokensIssued,
+		eventstore.TypeTokensIssued,
+		eventstore.TypeTransferRequested,
+		eventstore.TypeTransferCompleted,
+		eventstore.TypeTransferRequested,
+		eventstore.TypeTransferRejected,
+		eventstore.TypeTransferRequested,
+		eventstore.TypeTransferCompleted,
+		eventstore.TypeTransferRequested,
+		eventstore.TypeTransferCompleted,
+	}, types)
+
+	result, err := eventstore.Replay(testDB)
+	assert.NoError(t, err)
+	assert.True(t, result.Match)
+	assert.Equal(t, int64(10), result.EventsReplayed)
+
+	var balances []db.EventBalance
+	testDB.Order("address").Find(&balances)
+	assert.Equal(t, []db.EventBalance{
+		{Address: senderAddress, Balance: 700},
+		{Address: recipientAddress, Balance: 100},
+		{Address: "0x0000000000000000000000000000000000000003", Balance: 300},
+	}, balances)
+}
+
+func TestReplayDetectsDivergence(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	mutation := SetUpEventSourcedDatabase(t, senderAddress, 1000, recipientAddress, 100)
+
+	_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	testDB.Model(&db.Transfer{}).Where("id = ?", 1).Update("amount", 250)
+
+	result, err := eventstore.Replay(testDB)
+	assert.NoError(t, err)
+	assert.False(t, result.Match)
+	assert.Equal(t, []int64{1}, result.MismatchedTransfers)
+	assert.Empty(t, result.BalanceMismatches)
+}
+
+func TestBootstrapStartsReplayFromCurrentBalances(t *testing.T) {
+	holderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	RestartDatabase()
+
+	_, err := migrate.Seed(testDB)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	plain := transfer.NewService(transfer.NewGormStore(testDB, testStoreOptions), transfer.Options{})
+	sourced := transfer.NewService(transfer.NewGormStore(testDB, testStoreOptions), transfer.Options{EventSourcing: true})
+
+	_, err = plain.Transfer(ctx, migrate.GenesisAddress, holderAddress, 100)
+	assert.NoError(t, err)
+
+	result, err := eventstore.Replay(testDB)
+	assert.NoError(t, err)
+	assert.False(t, result.Match)
+
+	bootstrapped, err := eventstore.Bootstrap(testDB)
+	assert.NoError(t, err)
+	assert.True(t, bootstrapped)
+
+	bootstrapped, err = eventstore.Bootstrap(testDB)
+	assert.NoError(t, err)
+	assert.False(t, bootstrapped)
+
+	_, err = sourced.Transfer(ctx, holderAddress, recipientAddress, 30)
+	assert.NoError(t, err)
+
+	result, err = eventstore.Replay(testDB)
+	assert.NoError(t, err)
+	assert.True(t, result.Match, "%+v", result)
+	assert.Equal(t, int64(1), result.SnapshotTransferID)
+
+	var balances []db.EventBalance
+	testDB.Order("address").Find(&balances)
+	assert.Equal(t, []db.EventBalance{
+		{Address: migrate.GenesisAddress, Balance: migrate.GenesisSupply - 100},
+		{Address: holderAddress, Balance: 70},
+		{Address: recipientAddress, Balance: 30},
+	}, balances)
+}
+
+func TestSuspendedEventSourcingIsBootstrappedAgain(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpEventSourcedDatabase(t, senderAddress, 1000, recipientAddress, 100)
+
+	_, err := eventstore.Bootstrap(testDB)
+	assert.NoError(t, err)
+
+	suspended, err := eventstore.Suspend(testDB)
+	assert.NoError(t, err)
+	assert.True(t, suspended)
+
+	suspended, err = eventstore.Suspend(testDB)
+	assert.NoError(t, err)
+	assert.False(t, suspended)
+
+	plain := transfer.NewService(transfer.NewGormStore(testDB, testStoreOptions), transfer.Options{})
+	_, err = plain.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	result, err := eventstore.Replay(testDB)
+	assert.NoError(t, err)
+	assert.False(t, result.Match)
+	assert.Equal(t, []int64{1}, result.MismatchedTransfers)
+
+	bootstrapped, err := eventstore.Bootstrap(testDB)
+	assert.NoError(t, err)
+	assert.True(t, bootstrapped)
+
+	result, err = eventstore.Replay(testDB)
+	assert.NoError(t, err)
+	assert.True(t, result.Match, "%+v", result)
+}
+
+func TestRebuildRestoresLiveTablesFromEvents(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	mutation := SetUpEventSourcedDatabase(t, senderAddress, 1000, recipientAddress, 100)
+
+	_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	testDB.Model(&db.Transfer{}).Where("id = ?", 1).Update("amount", 250)
+	testDB.Model(&db.Wallet{}).Where("address = ?", senderAddress).Update("balance", 5)
+
+	rebuilt, err := eventstore.Rebuild(testDB)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), rebuilt)
+
+	result, err := eventstore.Replay(testDB)
+	assert.NoError(t, err)
+	assert.True(t, result.Match, "%+v", result)
+
+	var sender db.Wallet
+	assert.NoError(t, testDB.First(&sender, "address = ?", senderAddress).Error)
+	assert.Equal(t, int64(800), sender.Balance)
+
+	var transferred db.Transfer
+	assert.NoError(t, testDB.First(&transferred, 1).Error)
+	assert.Equal(t, int64(200), transferred.Amount)
+}
+
+func SetUpEventSourcedDatabase(t *testing.T, senderAddress string, senderBalance int64, recipientAddress string, recipientBalance int64) graph.MutationResolver {
+	RestartDatabase()
+
+	assert.NoError(t, eventstore.Issue(testDB, senderAddress, senderBalance))
+	assert.NoError(t, eventstore.Issue(testDB, recipientAddress, recipientBalance))
+
+	service := transfer.NewService(transfer.NewGormStore(testDB, testStoreOptions), transfer.Options{EventSourcing: true})
+
+	resolver := &graph.Resolver{DB: testDB, Transfers: service}
+	return resolver.Mutation()
+}
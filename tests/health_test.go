@@ -0,0 +1,82 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadiness(t *testing.T) {
+	workers := health.NewWorkers()
+	stop := make(chan struct{})
+	workers.Go("queue", func(context.Context) { <-stop })
+
+	response, report := GetReadiness(t, health.NewChecker(testDB, workers))
+
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, health.StatusReady, report.Status)
+	assert.Equal(t, health.StatusOK, report.Database.Status)
+	assert.Equal(t, health.StatusOK, report.Migrations.Status)
+	assert.Equal(t, health.StatusOK, report.Workers.Status)
+	assert.Equal(t, health.WorkerStatus{Started: 1, Running: 1}, report.Workers.Workers["queue"])
+
+	close(stop)
+	assert.NoError(t, workers.Wait(context.Background()))
+
+	response, report = GetReadiness(t, health.NewChecker(testDB, workers))
+
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	assert.Equal(t, health.StatusNotReady, report.Status)
+	assert.Equal(t, health.StatusFailing, report.Workers.Status)
+	assert.Equal(t, "stopped: queue", report.Workers.Error)
+}
+
+func TestReadinessRequiresMigrations(t *testing.T) {
+	database, err := db.OpenSQLite(filepath.Join(t.TempDir(), "unmigrated.db"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	response, report := GetReadiness(t, health.NewChecker(database, health.NewWorkers()))
+
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	assert.Equal(t, health.StatusOK, report.Database.Status)
+	assert.Equal(t, health.StatusFailing, report.Migrations.Status)
+	assert.Contains(t, report.Migrations.Error, "pending")
+}
+
+func TestReadinessRequiresDatabase(t *testing.T) {
+	database, err := db.OpenSQLite(filepath.Join(t.TempDir(), "closed.db"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	sqlDB, err := database.DB()
+	assert.NoError(t, err)
+	assert.NoError(t, sqlDB.Close())
+
+	response, report := GetReadiness(t, health.NewChecker(database, health.NewWorkers()))
+
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	assert.Equal(t, health.StatusFailing, report.Database.Status)
+	assert.NotEmpty(t, report.Database.Error)
+}
+
+func GetReadiness(t *testing.T, checker *health.Checker) (*httptest.ResponseRecorder, *health.Report) {
+	response := httptest.NewRecorder()
+	health.ReadinessHandler(checker).ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report health.Report
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &report))
+
+	return response, &report
+}
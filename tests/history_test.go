@@ -0,0 +1,106 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalanceAtWithAndWithoutCheckpoint(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpHistory(t, senderAddress, recipientAddress)
+
+	assertBalancesAt := func() {
+		AssertBalanceAt(t, senderAddress, "2024-12-31T00:00:00Z", 0)
+		AssertBalanceAt(t, senderAddress, "2025-01-15T00:00:00Z", 1000)
+		AssertBalanceAt(t, senderAddress, "2025-01-31T23:59:59Z", 800)
+		AssertBalanceAt(t, senderAddress, "2025-03-01T00:00:00Z", 750)
+		AssertBalanceAt(t, recipientAddress, "2025-03-01T00:00:00Z", 350)
+	}
+
+	assertBalancesAt()
+
+	_, err := ledger.TakeCheckpoint(testDB, MustParseTime(t, "2025-02-01T00:00:00Z"))
+	assert.NoError(t, err)
+
+	var stored []db.CheckpointBalance
+	testDB.Order("account").Find(&stored)
+	assert.Equal(t, []db.CheckpointBalance{
+		{CheckpointID: 1, Account: senderAddress, Balance: 800},
+		{CheckpointID: 1, Account: recipientAddress, Balance: 300},
+		{CheckpointID: 1, Account: ledger.IssuanceAccount, Balance: -1100},
+	}, stored)
+
+	assertBalancesAt()
+
+	_, err = ledger.TakeCheckpoint(testDB, MustParseTime(t, "2025-03-01T00:00:00Z"))
+	assert.NoError(t, err)
+
+	assertBalancesAt()
+}
+
+func TestTakeCheckpointRejectsOutOfOrderCheckpoints(t *testing.T) {
+	SetUpDatabase(t, "0x0000000000000000000000000000000000000001", 1000, "", 0)
+
+	_, err := ledger.TakeCheckpoint(testDB, MustParseTime(t, "2025-02-01T00:00:00Z"))
+	assert.NoError(t, err)
+
+	_, err = ledger.TakeCheckpoint(testDB, MustParseTime(t, "2025-01-01T00:00:00Z"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not after the latest checkpoint")
+}
+
+func TestWalletsSnapshot(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpHistory(t, senderAddress, recipientAddress)
+
+	_, err := ledger.TakeCheckpoint(testDB, MustParseTime(t, "2025-01-20T00:00:00Z"))
+	assert.NoError(t, err)
+
+	snapshot, err := ledger.SnapshotAt(testDB, MustParseTime(t, "2025-02-01T00:00:00Z"))
+	assert.NoError(t, err)
+	assert.Equal(t, []ledger.AccountBalance{
+		{Address: senderAddress, Balance: 800},
+		{Address: recipientAddress, Balance: 300},
+	}, snapshot)
+}
+
+// SetUpHistory issues 1000 and 100 tokens on 2025-01-01, transfers 200 on
+// 2025-01-31 and 50 on 2025-02-15.
+func SetUpHistory(t *testing.T, senderAddress string, recipientAddress string) {
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+
+	_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	_, err = mutation.Transfer(context.Background(), senderAddress, recipientAddress, 50)
+	assert.NoError(t, err)
+
+	times := []string{"2025-01-01T00:00:00Z", "2025-01-01T00:00:00Z", "2025-01-31T12:00:00Z", "2025-02-15T00:00:00Z"}
+	for i, at := range times {
+		err := testDB.Model(&db.JournalEntry{}).Where("id = ?", i+1).Update("created_at", MustParseTime(t, at)).Error
+		assert.NoError(t, err)
+	}
+}
+
+func AssertBalanceAt(t *testing.T, address string, at string, expected int64) {
+	balance, err := ledger.BalanceAt(testDB, address, MustParseTime(t, at))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, balance, "balance of %s at %s", address, at)
+}
+
+func MustParseTime(t *testing.T, value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	assert.NoError(t, err)
+	return parsed
+}
@@ -0,0 +1,128 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/audit"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestJournalMatchesBalancesAfterTransfers(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+
+	_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	_, err = mutation.ReverseTransfer(context.Background(), strconv.FormatInt(LastTransfer(t).ID, 10), "refund", nil)
+	assert.NoError(t, err)
+
+	_, err = mutation.Transfer(context.Background(), recipientAddress, senderAddress, 50)
+	assert.NoError(t, err)
+
+	result, err := ledger.Check(testDB)
+	assert.NoError(t, err)
+	assert.True(t, result.Consistent)
+	assert.Empty(t, result.Mismatches)
+
+	var lines []db.JournalLine
+	testDB.Joins("JOIN journal_entries ON journal_entries.id = journal_lines.entry_id").
+		Where("journal_entries.transfer_id = ?", LastTransfer(t).ID).
+		Find(&lines)
+
+	assert.Len(t, lines, 2)
+	assert.Equal(t, int64(0), lines[0].Amount+lines[1].Amount)
+}
+
+func TestPostRejectsUnbalancedEntry(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+
+	err := testDB.Transaction(func(tx *gorm.DB) error {
+		_, err := ledger.Post(tx, ledger.KindTransfer, nil,
+			ledger.Leg{Account: senderAddress, Amount: -100},
+			ledger.Leg{Account: recipientAddress, Amount: 99},
+		)
+		return err
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unbalanced")
+
+	var sender db.Wallet
+	testDB.First(&sender, "address = ?", senderAddress)
+	assert.Equal(t, int64(1000), sender.Balance)
+}
+
+func TestCheckDetectsAndRebuildRepairsDrift(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+	testDB.Model(&db.Wallet{}).Where("address = ?", recipientAddress).Update("balance", 500)
+
+	result, err := ledger.Check(testDB)
+	assert.NoError(t, err)
+	assert.False(t, result.Consistent)
+	assert.Equal(t, []ledger.Discrepancy{{Address: recipientAddress, Stored: 500, Computed: 100}}, result.Mismatches)
+
+	updated, err := ledger.Rebuild(testDB)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), updated)
+
+	result, err = ledger.Check(testDB)
+	assert.NoError(t, err)
+	assert.True(t, result.Consistent)
+}
+
+func TestOpenBalancesCarriesBalancesIntoJournal(t *testing.T) {
+	legacyAddress := "0x0000000000000000000000000000000000000001"
+	journaledAddress := "0x0000000000000000000000000000000000000002"
+
+	RestartDatabase()
+	assert.NoError(t, CreateWallet(t, journaledAddress, 100))
+
+	// Wallets funded before the journal existed hold a balance it lacks.
+	assert.NoError(t, testDB.Create(&db.Wallet{Address: legacyAddress, Balance: 1000}).Error)
+
+	result, err := ledger.Check(testDB)
+	assert.NoError(t, err)
+	assert.False(t, result.Consistent)
+
+	var openings []ledger.Opening
+	err = testDB.Transaction(func(tx *gorm.DB) error {
+		openings, err = ledger.OpenBalances(tx)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []ledger.Opening{{Address: legacyAddress, Amount: 1000}}, openings)
+
+	assert.Equal(t, int64(1000), FindWallet(t, legacyAddress).Balance)
+
+	result, err = ledger.Check(testDB)
+	assert.NoError(t, err)
+	assert.True(t, result.Consistent)
+	AssertReconciled(t)
+
+	report, err := audit.Verify(testDB, 0, 0)
+	assert.NoError(t, err)
+	assert.True(t, report.Valid)
+
+	err = testDB.Transaction(func(tx *gorm.DB) error {
+		openings, err = ledger.OpenBalances(tx)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, openings)
+}
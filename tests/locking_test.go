@@ -0,0 +1,299 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/graph"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var lockingStrategies = []transfer.Locking{transfer.LockingPessimistic, transfer.LockingOptimistic}
+
+func TestBalanceChangesIncrementWalletVersion(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	for _, locking := range lockingStrategies {
+		t.Run(string(locking), func(t *testing.T) {
+			SetUpDatabase(t, senderAddress, 1000, recipientAddress, 0)
+			mutation := CreateLockingResolver(locking)
+
+			_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 100)
+			assert.NoError(t, err)
+
+			sender := FindWallet(t, senderAddress)
+			recipient := FindWallet(t, recipientAddress)
+
+			assert.Equal(t, int64(900), sender.Balance)
+			assert.Equal(t, int64(2), sender.Version, "issuance and transfer")
+			assert.Equal(t, int64(100), recipient.Balance)
+			assert.Equal(t, int64(1), recipient.Version)
+		})
+	}
+}
+
+func TestPostVersionedRejectsStaleWallets(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpDatabase(t, senderAddress, 1000, recipientAddress, 0)
+
+	stale := FindWallet(t, senderAddress)
+	recipient := FindWallet(t, recipientAddress)
+
+	assert.NoError(t, ledger.Issue(testDB, senderAddress, 10))
+
+	legs := []ledger.Leg{{Account: senderAddress, Amount: -100}, {Account: recipientAddress, Amount: 100}}
+
+	err := testDB.Transaction(func(tx *gorm.DB) error {
+		_, err := ledger.PostVersioned(tx, ledger.KindTransfer, nil, []*db.Wallet{stale, recipient}, legs...)
+		return err
+	})
+	assert.ErrorIs(t, err, db.ErrVersionConflict)
+
+	err = testDB.Transaction(func(tx *gorm.DB) error {
+		_, err := ledger.PostVersioned(tx, ledger.KindTransfer, nil, []*db.Wallet{FindWallet(t, senderAddress), recipient}, legs...)
+		return err
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(910), FindWallet(t, senderAddress).Balance)
+	assert.Equal(t, int64(100), FindWallet(t, recipientAddress).Balance)
+	AssertReconciled(t)
+}
+
+func TestConcurrentTransfersWithEachLockingStrategy(t *testing.T) {
+	for _, locking := range lockingStrategies {
+		t.Run(string(locking), func(t *testing.T) {
+			for _, pattern := range contentionPatterns {
+				t.Run(pattern.name, func(t *testing.T) {
+					wallets := SetUpContention(t, pattern, 1000)
+					mutation := CreateLockingResolver(locking)
+
+					var wg sync.WaitGroup
+					errs := make(chan error, pattern.workers*10)
+
+					for worker := 0; worker < pattern.workers; worker++ {
+						wg.Add(1)
+
+						go func(worker int) {
+							defer wg.Done()
+
+							for i := 0; i < 10; i++ {
+								from, to := pattern.pick(wallets, worker, i)
+								if _, err := mutation.Transfer(context.Background(), from, to, 1); err != nil {
+									errs <- err
+								}
+							}
+						}(worker)
+					}
+
+					wg.Wait()
+					close(errs)
+
+					for err := range errs {
+						if locking == transfer.LockingOptimistic {
+							assert.ErrorIs(t, err, db.ErrVersionConflict, "only conflicts that outlast the retries may fail")
+						} else {
+							assert.NoError(t, err)
+						}
+					}
+
+					var total int64
+					testDB.Table("wallets w").Select("SUM(" + ledger.StoredBalanceSQL + ")").Scan(&total)
+					assert.Equal(t, int64(len(wallets)*1000), total)
+
+					AssertReconciled(t)
+				})
+			}
+		})
+	}
+}
+
+// BenchmarkTransfers compares the throughput of the locking strategies under
+// the contention patterns of the concurrency tests. Run it against Postgres
+// with
+//
+//	go test -tags integration -run '^$' -bench Transfers ./tests/
+//
+// and set DB_ISOLATION to compare isolation levels as well. retries/op is the
+// number of transactions that were run again after a conflict.
+func BenchmarkTransfers(b *testing.B) {
+	for _, locking := range lockingStrategies {
+		for _, pattern := range contentionPatterns {
+			b.Run(fmt.Sprintf("%s/%s", locking, pattern.name), func(b *testing.B) {
+				wallets := SetUpContention(b, pattern, int64(b.N)+1)
+				mutation := CreateLockingResolver(locking)
+
+				var worker atomic.Int64
+				var failed atomic.Int64
+				before := TotalRetries()
+
+				b.SetParallelism(pattern.workers)
+				b.ResetTimer()
+
+				b.RunParallel(func(pb *testing.PB) {
+					id := int(worker.Add(1))
+
+					for i := 0; pb.Next(); i++ {
+						from, to := pattern.pick(wallets, id, i)
+						if _, err := mutation.Transfer(context.Background(), from, to, 1); err != nil {
+							failed.Add(1)
+						}
+					}
+				})
+
+				b.StopTimer()
+
+				b.ReportMetric(float64(TotalRetries()-before)/float64(b.N), "retries/op")
+				b.ReportMetric(float64(failed.Load())/float64(b.N), "failures/op")
+			})
+		}
+	}
+}
+
+// contentionPattern decides which wallets each transfer of a concurrency test
+// or benchmark moves tokens between.
+type contentionPattern struct {
+	name    string
+	wallets int
+	workers int
+
+	// shards makes the first wallet a hot wallet with this many shards.
+	shards int
+
+	pick func(wallets []string, worker int, i int) (from string, to string)
+}
+
+var contentionPatterns = []contentionPattern{
+	{
+		// Every transfer debits the same wallet, as in TestConcurrentTransfers.
+		name:    "same-sender",
+		wallets: 2,
+		workers: 4,
+		pick: func(wallets []string, worker int, i int) (string, string) {
+			return wallets[0], wallets[1]
+		},
+	},
+	{
+		// Transfers go both ways between two wallets, as in
+		// TestBidirectionalConcurrentTransfers.
+		name:    "bidirectional",
+		wallets: 2,
+		workers: 4,
+		pick: func(wallets []string, worker int, i int) (string, string) {
+			if (worker+i)%2 == 0 {
+				return wallets[0], wallets[1]
+			}
+			return wallets[1], wallets[0]
+		},
+	},
+	{
+		// Many senders pay into one hot wallet.
+		name:    "fan-in",
+		wallets: 9,
+		workers: 8,
+		pick: func(wallets []string, worker int, i int) (string, string) {
+			return wallets[1+worker%8], wallets[0]
+		},
+	},
+	{
+		// Every worker pays from its own wallet into a wallet that does not
+		// exist yet, the same one for the i-th transfer of every worker, so
+		// the workers race to create it.
+		name:    "new-recipient",
+		wallets: 8,
+		workers: 8,
+		pick: func(wallets []string, worker int, i int) (string, string) {
+			return wallets[worker%8], fmt.Sprintf("0x%040x", 0x1000+i)
+		},
+	},
+	{
+		// One wallet pays every other wallet.
+		name:    "fan-out",
+		wallets: 9,
+		workers: 8,
+		pick: func(wallets []string, worker int, i int) (string, string) {
+			return wallets[0], wallets[1+worker%8]
+		},
+	},
+	{
+		// As fan-out, with the paying wallet split into shards.
+		name:    "fan-out-sharded",
+		wallets: 9,
+		workers: 8,
+		shards:  8,
+		pick: func(wallets []string, worker int, i int) (string, string) {
+			return wallets[0], wallets[1+worker%8]
+		},
+	},
+	{
+		// Every worker has its own pair of wallets.
+		name:    "disjoint",
+		wallets: 16,
+		workers: 8,
+		pick: func(wallets []string, worker int, i int) (string, string) {
+			pair := 2 * (worker % 8)
+			return wallets[pair], wallets[pair+1]
+		},
+	},
+}
+
+// SetUpContention creates the wallets of pattern, each holding balance tokens.
+func SetUpContention(tb testing.TB, pattern contentionPattern, balance int64) []string {
+	RestartDatabase()
+
+	wallets := make([]string, pattern.wallets)
+
+	for i := range wallets {
+		wallets[i] = fmt.Sprintf("0x%040x", i+1)
+
+		if err := testDB.Create(&db.Wallet{Address: wallets[i]}).Error; err != nil {
+			tb.Fatalf("failed to create wallet: %v", err)
+		}
+
+		if err := ledger.Issue(testDB, wallets[i], balance); err != nil {
+			tb.Fatalf("failed to issue tokens: %v", err)
+		}
+	}
+
+	if pattern.shards > 0 {
+		if err := ledger.ConfigureShards(testDB, wallets[:1], pattern.shards); err != nil {
+			tb.Fatalf("failed to configure shards: %v", err)
+		}
+	}
+
+	return wallets
+}
+
+func CreateLockingResolver(locking transfer.Locking) graph.MutationResolver {
+	options := testStoreOptions
+	options.Locking = locking
+
+	resolver := &graph.Resolver{DB: testDB, Transfers: transfer.NewService(transfer.NewGormStore(testDB, options), transfer.Options{})}
+	return resolver.Mutation()
+}
+
+func FindWallet(t *testing.T, address string) *db.Wallet {
+	var wallet db.Wallet
+	assert.NoError(t, testDB.First(&wallet, "address = ?", address).Error)
+	return &wallet
+}
+
+func TotalRetries() int64 {
+	var total int64
+	for _, count := range db.TransactionRetryStats().Retried {
+		total += count
+	}
+	return total
+}
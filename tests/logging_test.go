@@ -0,0 +1,98 @@
+//go:build integration
+
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/config"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/logging"
+	"github.com/dominika232323/token-transfer-api/internal/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolverErrorsAreLoggedWithRequestID(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpDatabase(t, senderAddress, 100, recipientAddress, 0)
+	output := CaptureLogs(t, config.Log{Level: "debug", Format: "json"})
+
+	previous := testDB.Logger
+	testDB.Logger = db.NewLogger(0)
+	t.Cleanup(func() { testDB.Logger = previous })
+
+	srv := NewGraphQLServer(CreateQueueResolver())
+	srv.SetErrorPresenter(logging.ErrorPresenter)
+
+	body := `{"query": "mutation { transfer(from_address: \"` + senderAddress + `\", to_address: \"` + recipientAddress + `\", amount: 1000) }"}`
+	request := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(logging.RequestIDHeader, "transfer-42")
+
+	response := httptest.NewRecorder()
+	logging.Middleware(srv).ServeHTTP(response, request)
+	assert.Equal(t, "transfer-42", response.Header().Get(logging.RequestIDHeader))
+
+	var result struct {
+		Errors []struct {
+			Message    string         `json:"message"`
+			Extensions map[string]any `json:"extensions"`
+		} `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+
+	if assert.Len(t, result.Errors, 1) {
+		assert.Equal(t, "Insufficient balance", result.Errors[0].Message)
+		assert.Equal(t, "transfer-42", result.Errors[0].Extensions["request_id"])
+	}
+
+	var failures, queries int
+	for _, record := range DecodeLogs(t, output) {
+		switch record["msg"] {
+		case "GraphQL resolver failed":
+			failures++
+			assert.Equal(t, "transfer-42", record["request_id"])
+			assert.Equal(t, "transfer", record["path"])
+		case "Query":
+			queries++
+			assert.Equal(t, "transfer-42", record["request_id"], "statement %v should carry the request ID", record["sql"])
+		}
+	}
+
+	assert.Equal(t, 1, failures)
+	assert.NotZero(t, queries)
+}
+
+func TestQueryLogsOmitWebhookSecrets(t *testing.T) {
+	RestartDatabase()
+	output := CaptureLogs(t, config.Log{Level: "debug", Format: "json"})
+
+	previous := testDB.Logger
+	testDB.Logger = db.NewLogger(0)
+	t.Cleanup(func() { testDB.Logger = previous })
+
+	registration, err := webhook.Register(testDB, "https://example.com/hooks")
+	assert.NoError(t, err)
+
+	rotated, err := webhook.RotateSecret(testDB, registration.ID)
+	assert.NoError(t, err)
+
+	queries := 0
+	for _, record := range DecodeLogs(t, output) {
+		if record["msg"] != "Query" {
+			continue
+		}
+
+		queries++
+		assert.NotContains(t, record["sql"], registration.Secret)
+		assert.NotContains(t, record["sql"], rotated.Secret)
+	}
+
+	assert.NotZero(t, queries)
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryTransfer(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	store, service := SetUpMemoryStore(senderAddress, 1000, recipientAddress, 100)
+
+	result, err := service.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(800), result.SenderBalance)
+	assert.Equal(t, int64(1), result.Transfer.ID)
+
+	AssertStoreBalance(t, store, senderAddress, 800)
+	AssertStoreBalance(t, store, recipientAddress, 300)
+}
+
+func TestMemoryTransferErrors(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+	unknownAddress := "0x0000000000000000000000000000000000000003"
+
+	store, service := SetUpMemoryStore(senderAddress, 100, recipientAddress, 100)
+
+	_, err := service.Transfer(context.Background(), senderAddress, recipientAddress, -1)
+	assert.ErrorIs(t, err, transfer.ErrNegativeAmount)
+
+	_, err = service.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.EqualError(t, err, "Insufficient balance")
+
+	_, err = service.Transfer(context.Background(), unknownAddress, recipientAddress, 10)
+	assert.EqualError(t, err, "Insufficient balance")
+
+	_, err = service.Transfer(context.Background(), senderAddress, senderAddress, 200)
+	assert.EqualError(t, err, "Insufficient balance")
+
+	_, err = service.Transfer(context.Background(), senderAddress, recipientAddress+"0", 10)
+	assert.ErrorIs(t, err, transfer.ErrInvalidAddress)
+
+	_, err = service.GetWallet(context.Background(), senderAddress+"0")
+	assert.ErrorIs(t, err, transfer.ErrInvalidAddress)
+
+	AssertStoreBalance(t, store, senderAddress, 100)
+	AssertStoreBalance(t, store, recipientAddress, 100)
+
+	_, err = store.GetWallet(context.Background(), unknownAddress)
+	assert.ErrorIs(t, err, transfer.ErrWalletNotFound, "A rolled back transfer must not create wallets")
+}
+
+func TestErrorCodes(t *testing.T) {
+	assert.Equal(t, "", transfer.ErrorCode(nil))
+	assert.Equal(t, "insufficient_balance", transfer.ErrorCode(transfer.ErrInsufficientBalance))
+	assert.Equal(t, "insufficient_balance_to_reverse", transfer.ErrorCode(transfer.ErrInsufficientBalanceToReverse))
+	assert.Equal(t, "invalid_address", transfer.ErrorCode(transfer.ErrInvalidAddress))
+	assert.Equal(t, "internal", transfer.ErrorCode(errors.New("connection reset")))
+}
+
+func TestMemoryTransferToUnknownRecipient(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	store, service := SetUpMemoryStore(senderAddress, 1000, "", 0)
+
+	_, err := service.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+
+	assert.NoError(t, err)
+	AssertStoreBalance(t, store, recipientAddress, 200)
+}
+
+func TestMemoryReverseTransfer(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	store, service := SetUpMemoryStore(senderAddress, 1000, recipientAddress, 0)
+
+	result, err := service.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	reversal, err := service.Reverse(context.Background(), result.Transfer.ID, "refund", false)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Transfer.ID, *reversal.ReversalOf)
+
+	_, err = service.Reverse(context.Background(), result.Transfer.ID, "refund", false)
+	assert.ErrorIs(t, err, transfer.ErrTransferAlreadyReversed)
+
+	_, err = service.Reverse(context.Background(), reversal.ID, "refund", false)
+	assert.ErrorIs(t, err, transfer.ErrCannotReverseReversal)
+
+	_, err = service.Reverse(context.Background(), 42, "refund", false)
+	assert.ErrorIs(t, err, transfer.ErrTransferNotFound)
+
+	AssertStoreBalance(t, store, senderAddress, 1000)
+	AssertStoreBalance(t, store, recipientAddress, 0)
+}
+
+func TestMemoryHistory(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, service := SetUpMemoryStore(senderAddress, 1000, recipientAddress, 0)
+
+	for _, amount := range []int64{10, 20, 30} {
+		_, err := service.Transfer(context.Background(), senderAddress, recipientAddress, amount)
+		assert.NoError(t, err)
+	}
+
+	page, err := service.History(context.Background(), recipientAddress, 2, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, page, 2) {
+		assert.Equal(t, int64(30), page[0].Amount)
+		assert.Equal(t, int64(20), page[1].Amount)
+	}
+
+	page, err = service.History(context.Background(), recipientAddress, 2, page[1].ID)
+	assert.NoError(t, err)
+	if assert.Len(t, page, 1) {
+		assert.Equal(t, int64(10), page[0].Amount)
+	}
+
+	_, err = service.History(context.Background(), "0x0000000000000000000000000000000000000003", 2, 0)
+	assert.ErrorIs(t, err, transfer.ErrWalletNotFound)
+}
+
+func TestMemoryEventSourcing(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	store := transfer.NewMemoryStore()
+	store.Issue(senderAddress, 100)
+	service := transfer.NewService(store, transfer.Options{EventSourcing: true})
+
+	_, err := service.Transfer(context.Background(), senderAddress, recipientAddress, 50)
+	assert.NoError(t, err)
+
+	_, err = service.Transfer(context.Background(), senderAddress, recipientAddress, 500)
+	assert.Error(t, err)
+
+	assert.Equal(t, 4, store.EventCount(""), "Each request should record TransferRequested and its outcome")
+}
+
+func SetUpMemoryStore(senderAddress string, senderBalance int64, recipientAddress string, recipientBalance int64) (*transfer.MemoryStore, transfer.Service) {
+	store := transfer.NewMemoryStore()
+	store.Issue(senderAddress, senderBalance)
+
+	if recipientAddress != "" {
+		store.Issue(recipientAddress, recipientBalance)
+	}
+
+	return store, transfer.NewService(store, transfer.Options{})
+}
@@ -0,0 +1,102 @@
+//go:build integration
+
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/dominika232323/token-transfer-api/graph"
+	"github.com/dominika232323/token-transfer-api/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferMetrics(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpDatabase(t, senderAddress, 100, recipientAddress, 0)
+	srv := NewGraphQLServer(CreateQueueResolver())
+
+	completed := metrics.Transfers.WithLabelValues("transfer", "completed", "")
+	insufficient := metrics.Transfers.WithLabelValues("transfer", "failed", "insufficient_balance")
+	succeeded := metrics.GraphQLOperations.WithLabelValues("mutation", "transfer", "ok")
+	failed := metrics.GraphQLOperations.WithLabelValues("mutation", "transfer", "error")
+
+	before := []float64{testutil.ToFloat64(completed), testutil.ToFloat64(insufficient),
+		testutil.ToFloat64(succeeded), testutil.ToFloat64(failed)}
+
+	mutation := `mutation { transfer(from_address: "` + senderAddress + `", to_address: "` + recipientAddress + `", amount: %s) }`
+
+	response := ServeGraphQL(srv, strings.Replace(mutation, "%s", "60", 1))
+	assert.Empty(t, response.Errors)
+
+	response = ServeGraphQL(srv, strings.Replace(mutation, "%s", "60", 1))
+	assert.NotEmpty(t, response.Errors)
+
+	assert.Equal(t, before[0]+1, testutil.ToFloat64(completed))
+	assert.Equal(t, before[1]+1, testutil.ToFloat64(insufficient))
+	assert.Equal(t, before[2]+1, testutil.ToFloat64(succeeded))
+	assert.Equal(t, before[3]+1, testutil.ToFloat64(failed))
+
+	sqlDB, err := testDB.DB()
+	assert.NoError(t, err)
+
+	var registered prometheus.AlreadyRegisteredError
+	if err := metrics.RegisterDatabase(sqlDB); !errors.As(err, &registered) {
+		assert.NoError(t, err)
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, `token_transfer_graphql_resolver_duration_seconds_count{field="transfer",object="Mutation"}`)
+	assert.Contains(t, body, `token_transfer_graphql_operation_duration_seconds_count{field="transfer",type="mutation"}`)
+	assert.Contains(t, body, `token_transfer_db_transaction_duration_seconds_count{outcome="commit"}`)
+	assert.Contains(t, body, `token_transfer_db_lock_wait_seconds_count{lock="wallet"}`)
+	assert.Contains(t, body, `go_sql_open_connections{db_name="token_transfer"}`)
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func NewGraphQLServer(resolver *graph.Resolver) *handler.Server {
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  resolver,
+		Directives: graph.NewDirectives(),
+	}))
+
+	srv.AddTransport(transport.POST{})
+	srv.Use(metrics.GraphQL{})
+
+	return srv
+}
+
+func ServeGraphQL(srv http.Handler, query string) *graphQLResponse {
+	body, _ := json.Marshal(map[string]string{"query": query})
+
+	request := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	request.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, request)
+
+	var response graphQLResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+
+	return &response
+}
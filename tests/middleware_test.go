@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/dominika232323/token-transfer-api/graph"
+	"github.com/dominika232323/token-transfer-api/internal/config"
+	"github.com/dominika232323/token-transfer-api/internal/logging"
+	"github.com/dominika232323/token-transfer-api/internal/server"
+	"github.com/stretchr/testify/assert"
+)
+
+type graphQLErrorBody struct {
+	Data   any `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func TestRecoverAnswersWithGraphQLError(t *testing.T) {
+	output := CaptureLogs(t, config.Log{Level: "info", Format: "json"})
+
+	h := logging.Middleware(server.Recover(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})))
+
+	request := httptest.NewRequest(http.MethodPost, "/query", nil)
+	request.Header.Set(logging.RequestIDHeader, "panicky-1")
+	response := httptest.NewRecorder()
+	h.ServeHTTP(response, request)
+
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+	assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+
+	body := DecodeGraphQLError(t, response)
+	assert.Nil(t, body.Data)
+	if assert.Len(t, body.Errors, 1) {
+		assert.Equal(t, "internal server error", body.Errors[0].Message)
+		assert.Equal(t, server.CodeInternal, body.Errors[0].Extensions["code"])
+		assert.Equal(t, "panicky-1", body.Errors[0].Extensions["request_id"])
+	}
+
+	records := DecodeLogs(t, output)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "HTTP handler panicked", records[0]["msg"])
+		assert.Equal(t, "boom", records[0]["panic"])
+		assert.Equal(t, "panicky-1", records[0]["request_id"])
+		assert.Contains(t, records[0]["stack"], "runtime/debug.Stack")
+	}
+}
+
+func TestRecoverLetsAbortHandlerThrough(t *testing.T) {
+	h := server.Recover(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestResolverPanicsAreHidden(t *testing.T) {
+	CaptureLogs(t, config.Log{Level: "info", Format: "json"})
+
+	// Without a database every resolver that queries it panics.
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  &graph.Resolver{},
+		Directives: graph.NewDirectives(),
+	}))
+	srv.AddTransport(transport.POST{})
+	srv.SetRecoverFunc(server.RecoverResolver)
+
+	request := httptest.NewRequest(http.MethodPost, "/query",
+		strings.NewReader(`{"query": "{ wallet(address: \"0x0000000000000000000000000000000000000001\") { balance } }"}`))
+	request.Header.Set("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	srv.ServeHTTP(response, request)
+
+	body := DecodeGraphQLError(t, response)
+	if assert.Len(t, body.Errors, 1) {
+		assert.Equal(t, "internal server error", body.Errors[0].Message)
+		assert.Equal(t, server.CodeInternal, body.Errors[0].Extensions["code"])
+	}
+}
+
+func TestLimitBody(t *testing.T) {
+	var received string
+	h := server.LimitBody(16, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+	}))
+
+	response := httptest.NewRecorder()
+	h.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(`{"query":"{}"}`)))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, `{"query":"{}"}`, received)
+
+	for name, request := range map[string]*http.Request{
+		"declared length": httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(strings.Repeat("a", 17))),
+		"unknown length":  httptest.NewRequest(http.MethodPost, "/query", io.NopCloser(strings.NewReader(strings.Repeat("a", 17)))),
+	} {
+		t.Run(name, func(t *testing.T) {
+			received = ""
+			if name == "unknown length" {
+				request.ContentLength = -1
+			}
+
+			response := httptest.NewRecorder()
+			h.ServeHTTP(response, request)
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+			assert.Empty(t, received)
+
+			body := DecodeGraphQLError(t, response)
+			if assert.Len(t, body.Errors, 1) {
+				assert.Equal(t, "request body exceeds 16 bytes", body.Errors[0].Message)
+				assert.Equal(t, server.CodeRequestTooLarge, body.Errors[0].Extensions["code"])
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	served := false
+	h := server.NewCORS([]string{"https://wallet.example.com"}).Middleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { served = true }))
+
+	for _, test := range []struct {
+		name        string
+		method      string
+		origin      string
+		preflight   bool
+		status      int
+		served      bool
+		allowOrigin string
+	}{
+		{name: "same origin", method: http.MethodPost, status: http.StatusOK, served: true},
+		{name: "allowed origin", method: http.MethodPost, origin: "https://wallet.example.com", status: http.StatusOK, served: true, allowOrigin: "https://wallet.example.com"},
+		{name: "allowed preflight", method: http.MethodOptions, origin: "https://wallet.example.com", preflight: true, status: http.StatusNoContent, allowOrigin: "https://wallet.example.com"},
+		{name: "other origin", method: http.MethodPost, origin: "https://evil.example.com", status: http.StatusOK, served: true},
+		{name: "other preflight", method: http.MethodOptions, origin: "https://evil.example.com", preflight: true, status: http.StatusForbidden},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			served = false
+
+			request := httptest.NewRequest(test.method, "/query", nil)
+			if test.origin != "" {
+				request.Header.Set("Origin", test.origin)
+			}
+			if test.preflight {
+				request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+
+			response := httptest.NewRecorder()
+			h.ServeHTTP(response, request)
+
+			assert.Equal(t, test.status, response.Code)
+			assert.Equal(t, test.served, served)
+			assert.Equal(t, test.allowOrigin, response.Header().Get("Access-Control-Allow-Origin"))
+
+			if test.preflight && test.allowOrigin != "" {
+				assert.Contains(t, response.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+				assert.Contains(t, response.Header().Get("Access-Control-Allow-Methods"), http.MethodPost)
+			}
+		})
+	}
+}
+
+func TestCORSCheckOrigin(t *testing.T) {
+	cors := server.NewCORS([]string{"https://wallet.example.com"})
+
+	for origin, allowed := range map[string]bool{
+		"":                           true,
+		"http://api.example.com":     true,
+		"https://wallet.example.com": true,
+		"https://evil.example.com":   false,
+	} {
+		request := httptest.NewRequest(http.MethodGet, "http://api.example.com/query", nil)
+		if origin != "" {
+			request.Header.Set("Origin", origin)
+		}
+
+		assert.Equal(t, allowed, cors.CheckOrigin(request), origin)
+	}
+
+	assert.True(t, server.NewCORS([]string{"*"}).Allows("https://anywhere.example.com"))
+}
+
+func TestServerTimeouts(t *testing.T) {
+	cfg := config.Default().Server
+	api := server.New(http.NotFoundHandler(), nil, nil, cfg)
+
+	assert.Equal(t, 5*time.Second, api.HTTP.ReadHeaderTimeout)
+	assert.Equal(t, 15*time.Second, api.HTTP.ReadTimeout)
+	assert.Equal(t, 30*time.Second, api.HTTP.WriteTimeout)
+	assert.Equal(t, 2*time.Minute, api.HTTP.IdleTimeout)
+	assert.Equal(t, 30*time.Second, api.ShutdownTimeout)
+}
+
+func DecodeGraphQLError(t *testing.T, response *httptest.ResponseRecorder) *graphQLErrorBody {
+	var body graphQLErrorBody
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+
+	return &body
+}
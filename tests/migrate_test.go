@@ -0,0 +1,167 @@
+//go:build integration
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/audit"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/eventstore"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"github.com/dominika232323/token-transfer-api/internal/migrate"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestMigrationsMatchModels(t *testing.T) {
+	RestartDatabase()
+
+	models := []interface{}{
+		&db.Wallet{}, &db.WalletShard{}, &db.Transfer{}, &db.AuditRecord{}, &db.JournalEntry{}, &db.JournalLine{},
+		&db.BalanceCheckpoint{}, &db.CheckpointBalance{}, &db.Event{}, &db.EventBalance{}, &db.EventTransfer{},
+		&db.OutboxMessage{}, &db.WebhookEndpoint{}, &db.WebhookDelivery{}, &db.WebhookAttempt{}, &db.TransferRequest{},
+	}
+
+	for _, model := range models {
+		statement := &gorm.Statement{DB: testDB}
+		assert.NoError(t, statement.Parse(model))
+
+		if !assert.True(t, testDB.Migrator().HasTable(model), "missing table %s", statement.Table) {
+			continue
+		}
+
+		for _, field := range statement.Schema.Fields {
+			if field.DBName != "" {
+				assert.True(t, testDB.Migrator().HasColumn(model, field.DBName),
+					"missing column %s.%s", statement.Table, field.DBName)
+			}
+		}
+	}
+}
+
+func TestMigrateDownAndUp(t *testing.T) {
+	RestartDatabase()
+
+	statuses, err := migrate.List(testDB)
+	assert.NoError(t, err)
+
+	reverted, err := migrate.Down(testDB, len(statuses))
+	assert.NoError(t, err)
+	assert.Len(t, reverted, len(statuses))
+
+	assert.False(t, testDB.Migrator().HasTable(&db.Wallet{}))
+	assert.ErrorIs(t, migrate.Check(testDB), migrate.ErrNotMigrated)
+
+	applied, err := migrate.Up(testDB)
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(statuses))
+	assert.NoError(t, migrate.Check(testDB))
+
+	applied, err = migrate.Up(testDB)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func TestSeedIssuesGenesisSupplyOnce(t *testing.T) {
+	RestartDatabase()
+
+	seeded, err := migrate.Seed(testDB)
+	assert.NoError(t, err)
+	assert.True(t, seeded)
+
+	seeded, err = migrate.Seed(testDB)
+	assert.NoError(t, err)
+	assert.False(t, seeded)
+
+	var genesis db.Wallet
+	assert.NoError(t, testDB.First(&genesis, "address = ?", migrate.GenesisAddress).Error)
+	assert.Equal(t, int64(migrate.GenesisSupply), genesis.Balance)
+
+	AssertReconciled(t)
+}
+
+func TestSeedSkipsFundedWallets(t *testing.T) {
+	RestartDatabase()
+
+	// A wallet funded outside the journal, as by the old init scripts.
+	assert.NoError(t, testDB.Create(&db.Wallet{Address: "0x0000000000000000000000000000000000000001", Balance: 500}).Error)
+
+	seeded, err := migrate.Seed(testDB)
+	assert.NoError(t, err)
+	assert.False(t, seeded)
+
+	var genesis int64
+	assert.NoError(t, testDB.Model(&db.Wallet{}).Where("address = ?", migrate.GenesisAddress).Count(&genesis).Error)
+	assert.Zero(t, genesis)
+}
+
+func TestMigrationOpensBalancesThatPredateTheJournal(t *testing.T) {
+	address := "0x0000000000000000000000000000000000000001"
+
+	RestartDatabase()
+
+	reverted, err := migrate.Down(testDB, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, reverted, 1) {
+		assert.Equal(t, "opening_balances", reverted[0].Name)
+	}
+
+	assert.NoError(t, testDB.Create(&db.Wallet{Address: address, Balance: 1000}).Error)
+
+	applied, err := migrate.Up(testDB)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 1)
+
+	var wallet db.Wallet
+	assert.NoError(t, testDB.First(&wallet, "address = ?", address).Error)
+	assert.Equal(t, int64(1000), wallet.Balance)
+
+	var events []db.Event
+	assert.NoError(t, testDB.Where("stream_id = ?", eventstore.WalletStream(address)).Find(&events).Error)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, eventstore.TypeTokensIssued, events[0].Type)
+		assert.JSONEq(t, `{"address": "`+address+`", "amount": 1000}`, events[0].Payload)
+	}
+
+	var issuances int64
+	assert.NoError(t, testDB.Model(&db.AuditRecord{}).Where("operation = ? AND to_address = ?", audit.OperationIssuance, address).Count(&issuances).Error)
+	assert.Equal(t, int64(1), issuances)
+
+	seeded, err := migrate.Seed(testDB)
+	assert.NoError(t, err)
+	assert.False(t, seeded)
+
+	AssertReconciled(t)
+}
+
+func TestMigrateDownFoldsShardsBack(t *testing.T) {
+	hotAddress := "0x0000000000000000000000000000000000000001"
+	emptyAddress := "0x0000000000000000000000000000000000000002"
+
+	RestartDatabase()
+
+	assert.NoError(t, ledger.Issue(testDB, hotAddress, 1003))
+	assert.NoError(t, ledger.ConfigureShards(testDB, []string{hotAddress}, 4))
+
+	// A hot wallet whose shard rows are gone.
+	assert.NoError(t, testDB.Create(&db.Wallet{Address: emptyAddress, Balance: 200, Shards: 4}).Error)
+
+	reverted, err := migrate.Down(testDB, 3)
+	assert.NoError(t, err)
+	if assert.Len(t, reverted, 3) {
+		assert.Equal(t, "wallet_shards", reverted[2].Name)
+	}
+
+	balances := map[string]int64{}
+	for _, address := range []string{hotAddress, emptyAddress} {
+		var balance int64
+		assert.NoError(t, testDB.Table("wallets").Select("balance").Where("address = ?", address).Scan(&balance).Error)
+		balances[address] = balance
+	}
+
+	assert.Equal(t, map[string]int64{hotAddress: 1003, emptyAddress: 200}, balances)
+
+	_, err = migrate.Up(testDB)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,215 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/graph"
+	"github.com/dominika232323/token-transfer-api/graph/model"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/queue"
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitTransfer(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpDatabase(t, senderAddress, 100, recipientAddress, 0)
+	resolver := CreateQueueResolver()
+
+	submitted, err := resolver.Mutation().SubmitTransfer(context.Background(), senderAddress, recipientAddress, 60)
+	assert.NoError(t, err)
+	assert.Equal(t, model.TransferRequestStatusPending, submitted.Status)
+
+	rejected, err := resolver.Mutation().SubmitTransfer(context.Background(), senderAddress, recipientAddress, 50)
+	assert.NoError(t, err)
+
+	_, err = resolver.Mutation().SubmitTransfer(context.Background(), senderAddress, recipientAddress, -1)
+	assert.ErrorIs(t, err, transfer.ErrNegativeAmount)
+
+	assert.Equal(t, int64(100), FindWallet(t, senderAddress).Balance)
+
+	processed, err := NewTestPool().RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, processed)
+
+	status, err := resolver.Query().TransferStatus(context.Background(), submitted.ID)
+	if assert.NoError(t, err) && assert.NotNil(t, status) {
+		assert.Equal(t, model.TransferRequestStatusCompleted, status.Status)
+		assert.NotNil(t, status.TransferID)
+		assert.Equal(t, int64(40), *status.SenderBalance)
+		assert.NotNil(t, status.ProcessedAt)
+	}
+
+	status, err = resolver.Query().TransferStatus(context.Background(), rejected.ID)
+	if assert.NoError(t, err) && assert.NotNil(t, status) {
+		assert.Equal(t, model.TransferRequestStatusFailed, status.Status)
+		assert.Equal(t, "Insufficient balance", *status.Error)
+		assert.Nil(t, status.TransferID)
+	}
+
+	status, err = resolver.Query().TransferStatus(context.Background(), "42")
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+
+	assert.Equal(t, int64(40), FindWallet(t, senderAddress).Balance)
+	assert.Equal(t, int64(60), FindWallet(t, recipientAddress).Balance)
+	AssertReconciled(t)
+}
+
+func TestQueueBatchesPerSenderInOrder(t *testing.T) {
+	firstSender := "0x0000000000000000000000000000000000000001"
+	secondSender := "0x0000000000000000000000000000000000000002"
+	recipientAddress := "0x0000000000000000000000000000000000000003"
+
+	SetUpDatabase(t, firstSender, 100, secondSender, 100)
+
+	for _, amount := range []int64{60, 50, 40} {
+		for _, sender := range []string{firstSender, secondSender} {
+			_, err := queue.Submit(testDB, sender, recipientAddress, amount)
+			assert.NoError(t, err)
+		}
+	}
+
+	pool := NewTestPool()
+
+	processed, err := pool.RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, processed, "a batch holds the requests of one sender")
+
+	var requests []db.TransferRequest
+	testDB.Where("from_address = ?", firstSender).Order("id").Find(&requests)
+
+	statuses := []string{}
+	for _, request := range requests {
+		statuses = append(statuses, request.Status)
+	}
+
+	assert.Equal(t, []string{queue.StatusCompleted, queue.StatusFailed, queue.StatusCompleted}, statuses,
+		"requests are processed in the order they were submitted")
+
+	var pending int64
+	testDB.Model(&db.TransferRequest{}).Where("from_address = ? AND status = ?", secondSender, queue.StatusPending).Count(&pending)
+	assert.Equal(t, int64(3), pending)
+
+	processed, err = pool.RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, processed)
+
+	processed, err = pool.RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, processed)
+
+	assert.Equal(t, int64(200), FindWallet(t, recipientAddress).Balance)
+	AssertReconciled(t)
+}
+
+func TestQueueWorkersProcessEveryRequestOnce(t *testing.T) {
+	recipientAddress := "0x0000000000000000000000000000000000000099"
+
+	SetUpDatabase(t, recipientAddress, 0, "", 0)
+
+	senders := make([]string, 5)
+	for i := range senders {
+		senders[i] = fmt.Sprintf("0x%040x", i+1)
+		assert.NoError(t, CreateWallet(t, senders[i], 100))
+	}
+
+	var wg sync.WaitGroup
+
+	for i := range 100 {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			_, err := queue.Submit(testDB, senders[i%len(senders)], recipientAddress, 7)
+			assert.NoError(t, err)
+		}(i)
+	}
+
+	wg.Wait()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := NewTestPool()
+	pool.Workers = 4
+	pool.BatchSize = 5
+	pool.Start(ctx)
+
+	assert.Eventually(t, func() bool {
+		var pending int64
+		testDB.Model(&db.TransferRequest{}).Where("status = ?", queue.StatusPending).Count(&pending)
+		return pending == 0
+	}, 30*time.Second, 20*time.Millisecond)
+
+	cancel()
+
+	var completed, transfers int64
+	testDB.Model(&db.TransferRequest{}).Where("status = ?", queue.StatusCompleted).Count(&completed)
+	testDB.Model(&db.Transfer{}).Count(&transfers)
+
+	assert.Equal(t, int64(len(senders)*14), completed, "every sender can afford 14 transfers of 7")
+	assert.Equal(t, completed, transfers, "every completed request made exactly one transfer")
+
+	assert.Equal(t, completed*7, FindWallet(t, recipientAddress).Balance)
+	AssertReconciled(t)
+}
+
+func TestTransferStatusSubscription(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpDatabase(t, senderAddress, 100, recipientAddress, 0)
+	resolver := CreateQueueResolver()
+
+	defaultInterval := queue.WatchInterval
+	queue.WatchInterval = 10 * time.Millisecond
+	defer func() { queue.WatchInterval = defaultInterval }()
+
+	submitted, err := resolver.Mutation().SubmitTransfer(context.Background(), senderAddress, recipientAddress, 10)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := resolver.Subscription().TransferStatus(ctx, submitted.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, model.TransferRequestStatusPending, (<-updates).Status)
+
+	_, err = NewTestPool().RunOnce(context.Background())
+	assert.NoError(t, err)
+
+	select {
+	case update := <-updates:
+		assert.Equal(t, model.TransferRequestStatusCompleted, update.Status)
+		assert.Equal(t, int64(90), *update.SenderBalance)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update after the request was processed")
+	}
+
+	_, open := <-updates
+	assert.False(t, open, "the subscription ends once the request has completed")
+
+	_, err = resolver.Subscription().TransferStatus(ctx, "42")
+	assert.ErrorIs(t, err, queue.ErrRequestNotFound)
+}
+
+func CreateQueueResolver() *graph.Resolver {
+	return &graph.Resolver{DB: testDB, Transfers: transfer.NewService(transfer.NewGormStore(testDB, testStoreOptions), transfer.Options{})}
+}
+
+func NewTestPool() *queue.Pool {
+	return queue.NewPool(testDB, testStoreOptions, transfer.Options{})
+}
@@ -0,0 +1,59 @@
+//go:build integration
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/reconcile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconciliationReportsDrift(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+	_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	AssertReconciled(t)
+
+	testDB.Model(&db.Wallet{}).Where("address = ?", senderAddress).Update("balance", 850)
+
+	report, err := reconcile.Run(testDB)
+	assert.NoError(t, err)
+	assert.False(t, report.Reconciled)
+	assert.Equal(t, int64(1100), report.TotalSupply)
+	assert.Equal(t, int64(1150), report.TotalBalances)
+	assert.Equal(t, int64(50), report.SupplyDrift)
+	assert.Equal(t, []reconcile.WalletDrift{{
+		Address:       senderAddress,
+		StoredBalance: 850,
+		LedgerBalance: 800,
+		Drift:         50,
+	}}, report.WalletDrifts)
+
+	var text bytes.Buffer
+	assert.NoError(t, report.WriteText(&text))
+	assert.Contains(t, text.String(), "DRIFT DETECTED")
+	assert.Contains(t, text.String(), senderAddress)
+
+	var json bytes.Buffer
+	assert.NoError(t, report.WriteJSON(&json))
+	assert.Contains(t, json.String(), `"supply_drift": 50`)
+}
+
+func AssertReconciled(t *testing.T) {
+	report, err := reconcile.Run(testDB)
+	assert.NoError(t, err)
+
+	if assert.NotNil(t, report) {
+		assert.True(t, report.Reconciled, "wallet balances should reconcile with the ledger")
+		assert.Empty(t, report.WalletDrifts)
+		assert.Zero(t, report.SupplyDrift)
+	}
+}
@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/config"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := logging.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestID(r.Context())
+	}))
+
+	for _, test := range []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "taken from header", header: "client-request-1", expected: "client-request-1"},
+		{name: "generated when missing"},
+		{name: "generated when invalid", header: "has spaces in it"},
+		{name: "generated when too long", header: strings.Repeat("a", 129)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.header != "" {
+				request.Header.Set(logging.RequestIDHeader, test.header)
+			}
+
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, request)
+
+			if test.expected != "" {
+				assert.Equal(t, test.expected, seen)
+			} else {
+				assert.Len(t, seen, 32)
+				assert.NotEqual(t, test.header, seen)
+			}
+			assert.Equal(t, seen, response.Header().Get(logging.RequestIDHeader))
+		})
+	}
+}
+
+func TestLogRecordsCarryRequestID(t *testing.T) {
+	output := CaptureLogs(t, config.Log{Level: "info", Format: "json"})
+
+	ctx := logging.WithRequestID(context.Background(), "abc123")
+	slog.InfoContext(ctx, "handled")
+	slog.Debug("too verbose")
+
+	records := DecodeLogs(t, output)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "handled", records[0]["msg"])
+		assert.Equal(t, "abc123", records[0]["request_id"])
+	}
+}
+
+func TestTextLogFormat(t *testing.T) {
+	output := CaptureLogs(t, config.Log{Level: "debug", Format: "text"})
+
+	slog.Debug("starting", slog.Int("port", 8080))
+
+	assert.Contains(t, output.String(), "level=DEBUG msg=starting port=8080")
+}
+
+func TestErrorPresenterLogsRequestID(t *testing.T) {
+	output := CaptureLogs(t, config.Log{Level: "info", Format: "json"})
+
+	ctx := logging.WithRequestID(context.Background(), "abc123")
+	presented := logging.ErrorPresenter(ctx, errors.New("insufficient balance"))
+
+	assert.Equal(t, "insufficient balance", presented.Message)
+	assert.Equal(t, "abc123", presented.Extensions["request_id"])
+
+	records := DecodeLogs(t, output)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "WARN", records[0]["level"])
+		assert.Equal(t, "insufficient balance", records[0]["error"])
+		assert.Equal(t, "abc123", records[0]["request_id"])
+	}
+}
+
+func TestQueryLogger(t *testing.T) {
+	output := CaptureLogs(t, config.Log{Level: "debug", Format: "json"})
+	logger := db.NewLogger(100 * time.Millisecond)
+	statement := func() (string, int64) { return "SELECT 1", 1 }
+
+	logger.Trace(context.Background(), time.Now(), statement, nil)
+	logger.Trace(context.Background(), time.Now().Add(-time.Second), statement, nil)
+	logger.Trace(context.Background(), time.Now(), statement, errors.New("no such table"))
+
+	records := DecodeLogs(t, output)
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, "DEBUG", records[0]["level"])
+		assert.Equal(t, "SELECT 1", records[0]["sql"])
+		assert.Equal(t, "WARN", records[1]["level"])
+		assert.Equal(t, "Slow query", records[1]["msg"])
+		assert.Equal(t, "ERROR", records[2]["level"])
+		assert.Equal(t, "no such table", records[2]["error"])
+	}
+}
+
+func TestQueryLoggerSkipsStatementsBelowLevel(t *testing.T) {
+	output := CaptureLogs(t, config.Log{Level: "info", Format: "json"})
+	called := false
+
+	db.NewLogger(time.Second).Trace(context.Background(), time.Now(), func() (string, int64) {
+		called = true
+		return "SELECT 1", 1
+	}, nil)
+
+	assert.False(t, called)
+	assert.Empty(t, output.String())
+}
+
+// CaptureLogs sets up logging into the returned buffer for the rest of the
+// test.
+func CaptureLogs(t *testing.T, cfg config.Log) *bytes.Buffer {
+	previous := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		log.SetOutput(os.Stderr)
+	})
+
+	output := &bytes.Buffer{}
+	logging.Setup(cfg, output)
+
+	return output
+}
+
+func DecodeLogs(t *testing.T, output *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var record map[string]any
+		if assert.NoError(t, json.Unmarshal([]byte(line), &record)) {
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
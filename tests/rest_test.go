@@ -0,0 +1,168 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/graph"
+	"github.com/dominika232323/token-transfer-api/internal/rest"
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRESTTransfer(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+	api := rest.NewHandler(transfer.NewService(transfer.NewGormStore(testDB, testStoreOptions), transfer.Options{}))
+
+	recorder := ServeREST(api, http.MethodPost, "/v1/transfers",
+		`{"from_address":"`+senderAddress+`","to_address":"`+recipientAddress+`","amount":200}`)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+
+	var response struct {
+		Transfer struct {
+			ID     int64 `json:"id"`
+			Amount int64 `json:"amount"`
+		} `json:"transfer"`
+		Balance int64 `json:"balance"`
+	}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, int64(800), response.Balance)
+	assert.Equal(t, int64(200), response.Transfer.Amount)
+
+	recorder = ServeREST(api, http.MethodGet, "/v1/wallets/"+recipientAddress, "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"address":"`+recipientAddress+`","balance":300}`, recorder.Body.String())
+}
+
+func TestRESTTransferAboveInt32ReadsBackThroughGraphQL(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpDatabase(t, senderAddress, 10_000_000_000, recipientAddress, 0)
+	service := transfer.NewService(transfer.NewGormStore(testDB, testStoreOptions), transfer.Options{})
+
+	recorder := ServeREST(rest.NewHandler(service), http.MethodPost, "/v1/transfers",
+		`{"from_address":"`+senderAddress+`","to_address":"`+recipientAddress+`","amount":5000000000}`)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+
+	resolver := &graph.Resolver{DB: testDB, Transfers: service}
+
+	transfers, err := resolver.Query().Transfers(context.Background(), recipientAddress, nil, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, transfers, 1) {
+		assert.Equal(t, int64(5_000_000_000), transfers[0].Amount)
+	}
+
+	balance, err := resolver.Mutation().Transfer(context.Background(), senderAddress, recipientAddress, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4_999_999_999), balance)
+}
+
+func TestRESTTransferErrors(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpDatabase(t, senderAddress, 100, recipientAddress, 100)
+	api := rest.NewHandler(transfer.NewService(transfer.NewGormStore(testDB, testStoreOptions), transfer.Options{}))
+
+	recorder := ServeREST(api, http.MethodPost, "/v1/transfers",
+		`{"from_address":"`+senderAddress+`","to_address":"`+recipientAddress+`","amount":200}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.JSONEq(t, `{"error":"Insufficient balance"}`, recorder.Body.String())
+
+	recorder = ServeREST(api, http.MethodPost, "/v1/transfers",
+		`{"from_address":"`+senderAddress+`","to_address":"`+recipientAddress+`","amount":-5}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = ServeREST(api, http.MethodPost, "/v1/transfers", `{"from_address":"`+senderAddress+`"}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = ServeREST(api, http.MethodGet, "/v1/wallets/0x0000000000000000000000000000000000000009", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestRESTRejectsAddressesLongerThanTheSpecAllows(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	longAddress := senderAddress + "0"
+
+	SetUpDatabase(t, senderAddress, 100, "", 0)
+	api := rest.NewHandler(transfer.NewService(transfer.NewGormStore(testDB, testStoreOptions), transfer.Options{}))
+
+	recorder := ServeREST(api, http.MethodPost, "/v1/transfers",
+		`{"from_address":"`+senderAddress+`","to_address":"`+longAddress+`","amount":10}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.JSONEq(t, `{"error":"address must be at most 42 characters"}`, recorder.Body.String())
+
+	recorder = ServeREST(api, http.MethodGet, "/v1/wallets/"+longAddress, "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = ServeREST(api, http.MethodGet, "/v1/wallets/"+longAddress+"/transfers", "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	assert.Equal(t, int64(100), FindWallet(t, senderAddress).Balance)
+}
+
+func TestRESTTransferHistoryPagination(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+	api := rest.NewHandler(transfer.NewService(transfer.NewGormStore(testDB, testStoreOptions), transfer.Options{}))
+
+	for _, amount := range []int32{10, 20, 30} {
+		_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, amount)
+		assert.NoError(t, err)
+	}
+
+	var page struct {
+		Transfers []struct {
+			ID     int64 `json:"id"`
+			Amount int64 `json:"amount"`
+		} `json:"transfers"`
+		NextBefore *int64 `json:"next_before"`
+	}
+
+	recorder := ServeREST(api, http.MethodGet, "/v1/wallets/"+recipientAddress+"/transfers?limit=2", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+	assert.Len(t, page.Transfers, 2)
+	assert.Equal(t, int64(30), page.Transfers[0].Amount)
+
+	if assert.NotNil(t, page.NextBefore) {
+		recorder = ServeREST(api, http.MethodGet, "/v1/wallets/"+recipientAddress+"/transfers?limit=2&before="+
+			strconv.FormatInt(*page.NextBefore, 10), "")
+		page.Transfers, page.NextBefore = nil, nil
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+		assert.Len(t, page.Transfers, 1)
+		assert.Equal(t, int64(10), page.Transfers[0].Amount)
+		assert.Nil(t, page.NextBefore)
+	}
+}
+
+func TestRESTServesOpenAPIDocument(t *testing.T) {
+	api := rest.NewHandler(transfer.NewService(transfer.NewGormStore(testDB, testStoreOptions), transfer.Options{}))
+
+	recorder := ServeREST(api, http.MethodGet, "/v1/openapi.yaml", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	for _, path := range []string{"/v1/transfers:", "/v1/wallets/{address}:", "/v1/wallets/{address}/transfers:"} {
+		assert.Contains(t, recorder.Body.String(), path)
+	}
+}
+
+func ServeREST(api http.Handler, method string, target string, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	api.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return recorder
+}
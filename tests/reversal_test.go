@@ -0,0 +1,103 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReverseTransfer(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+	_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	original := LastTransfer(t)
+	reversal, err := mutation.ReverseTransfer(context.Background(), strconv.FormatInt(original.ID, 10), "customer refund", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, recipientAddress, reversal.FromAddress)
+	assert.Equal(t, senderAddress, reversal.ToAddress)
+	assert.Equal(t, int64(200), reversal.Amount)
+	assert.Equal(t, strconv.FormatInt(original.ID, 10), *reversal.ReversalOf)
+	assert.Equal(t, "customer refund", reversal.Reason)
+
+	var sender db.Wallet
+	testDB.First(&sender, "address = ?", senderAddress)
+	assert.Equal(t, int64(1000), sender.Balance)
+
+	var recipient db.Wallet
+	testDB.First(&recipient, "address = ?", recipientAddress)
+	assert.Equal(t, int64(100), recipient.Balance)
+}
+
+func TestReverseTransferTwice(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+	_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	id := strconv.FormatInt(LastTransfer(t).ID, 10)
+
+	_, err = mutation.ReverseTransfer(context.Background(), id, "first", nil)
+	assert.NoError(t, err)
+
+	_, err = mutation.ReverseTransfer(context.Background(), id, "second", nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "transfer already reversed")
+
+	var sender db.Wallet
+	testDB.First(&sender, "address = ?", senderAddress)
+	assert.Equal(t, int64(1000), sender.Balance)
+}
+
+func TestReverseTransferInsufficientBalance(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+	otherAddress := "0x0000000000000000000000000000000000000003"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, recipientAddress, 0)
+	_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	id := strconv.FormatInt(LastTransfer(t).ID, 10)
+
+	_, err = mutation.Transfer(context.Background(), recipientAddress, otherAddress, 150)
+	assert.NoError(t, err)
+
+	_, err = mutation.ReverseTransfer(context.Background(), id, "refund", nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Insufficient balance to reverse transfer")
+
+	allowNegative := true
+	_, err = mutation.ReverseTransfer(context.Background(), id, "refund", &allowNegative)
+	assert.NoError(t, err)
+
+	var recipient db.Wallet
+	testDB.First(&recipient, "address = ?", recipientAddress)
+	assert.Equal(t, int64(-150), recipient.Balance)
+}
+
+func TestReverseUnknownTransfer(t *testing.T) {
+	_, mutation := SetUpDatabase(t, "0x0000000000000000000000000000000000000001", 1000, "", 0)
+	_, err := mutation.ReverseTransfer(context.Background(), "42", "refund", nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "transfer not found")
+}
+
+func LastTransfer(t *testing.T) db.Transfer {
+	var transfer db.Transfer
+	err := testDB.Order("id DESC").First(&transfer).Error
+	assert.NoError(t, err)
+	return transfer
+}
@@ -0,0 +1,211 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/audit"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigureShardsSpreadsBalance(t *testing.T) {
+	hotAddress := "0x0000000000000000000000000000000000000001"
+
+	SetUpDatabase(t, hotAddress, 1003, "", 0)
+
+	assert.NoError(t, ledger.ConfigureShards(testDB, []string{hotAddress}, 4))
+
+	var shards []db.WalletShard
+	testDB.Where("address = ?", hotAddress).Order("shard").Find(&shards)
+
+	if assert.Len(t, shards, 4) {
+		assert.Equal(t, int64(253), shards[0].Balance)
+		assert.Equal(t, int64(250), shards[3].Balance)
+	}
+
+	stored := FindWallet(t, hotAddress)
+	assert.Equal(t, int64(0), stored.Balance)
+	assert.Equal(t, 4, stored.Shards)
+
+	wallet, err := transfer.NewGormStore(testDB, testStoreOptions).GetWallet(context.Background(), hotAddress)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1003), wallet.Balance, "the API reports the sum of the shards")
+
+	AssertReconciled(t)
+
+	assert.NoError(t, ledger.ConfigureShards(testDB, nil, 4))
+
+	stored = FindWallet(t, hotAddress)
+	assert.Equal(t, int64(1003), stored.Balance, "unconfigured hot wallets are folded back")
+	assert.Equal(t, 0, stored.Shards)
+
+	var remaining int64
+	testDB.Model(&db.WalletShard{}).Count(&remaining)
+	assert.Zero(t, remaining)
+
+	AssertReconciled(t)
+}
+
+func TestTransfersFromHotWallet(t *testing.T) {
+	hotAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	for _, locking := range lockingStrategies {
+		t.Run(string(locking), func(t *testing.T) {
+			SetUpDatabase(t, hotAddress, 1000, recipientAddress, 0)
+			assert.NoError(t, ledger.ConfigureShards(testDB, []string{hotAddress}, 4))
+
+			mutation := CreateLockingResolver(locking)
+
+			result, err := mutation.Transfer(context.Background(), hotAddress, recipientAddress, 100)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(900), result)
+
+			_, err = mutation.Transfer(context.Background(), hotAddress, recipientAddress, 600)
+			assert.NoError(t, err, "a debit larger than any shard is taken from several")
+
+			_, err = mutation.Transfer(context.Background(), hotAddress, recipientAddress, 301)
+			assert.EqualError(t, err, transfer.ErrInsufficientBalance.Error())
+
+			_, err = mutation.Transfer(context.Background(), recipientAddress, hotAddress, 50)
+			assert.NoError(t, err)
+
+			wallet, err := transfer.NewGormStore(testDB, testStoreOptions).GetWallet(context.Background(), hotAddress)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(350), wallet.Balance)
+			assert.Equal(t, int64(650), FindWallet(t, recipientAddress).Balance)
+
+			var records []db.AuditRecord
+			testDB.Order("id").Find(&records)
+			if assert.NotEmpty(t, records) {
+				last := records[len(records)-1]
+				assert.Equal(t, int64(350), last.ToBalance, "audit records hold the summed balance")
+			}
+
+			report, err := audit.Verify(testDB, 0, 0)
+			assert.NoError(t, err)
+			assert.True(t, report.Valid)
+
+			AssertReconciled(t)
+		})
+	}
+}
+
+func TestIssuanceToHotWalletAuditsSummedBalance(t *testing.T) {
+	hotAddress := "0x0000000000000000000000000000000000000001"
+
+	SetUpDatabase(t, hotAddress, 1000, "", 0)
+	assert.NoError(t, ledger.ConfigureShards(testDB, []string{hotAddress}, 4))
+
+	assert.NoError(t, ledger.Issue(testDB, hotAddress, 50))
+
+	var last db.AuditRecord
+	assert.NoError(t, testDB.Order("id DESC").First(&last).Error)
+	assert.Equal(t, audit.OperationIssuance, last.Operation)
+	assert.Equal(t, int64(1050), last.ToBalance)
+
+	report, err := audit.Verify(testDB, 0, 0)
+	assert.NoError(t, err)
+	assert.True(t, report.Valid)
+
+	AssertReconciled(t)
+}
+
+func TestReversalMayTakeHotWalletNegative(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	hotAddress := "0x0000000000000000000000000000000000000002"
+	otherAddress := "0x0000000000000000000000000000000000000003"
+
+	for _, locking := range lockingStrategies {
+		t.Run(string(locking), func(t *testing.T) {
+			SetUpDatabase(t, senderAddress, 1000, hotAddress, 0)
+			assert.NoError(t, ledger.ConfigureShards(testDB, []string{hotAddress}, 4))
+
+			store := transfer.NewGormStore(testDB, transfer.StoreOptions{Locking: locking, Isolation: testStoreOptions.Isolation})
+			service := transfer.NewService(store, transfer.Options{})
+
+			result, err := service.Transfer(context.Background(), senderAddress, hotAddress, 400)
+			assert.NoError(t, err)
+
+			_, err = service.Transfer(context.Background(), hotAddress, otherAddress, 300)
+			assert.NoError(t, err)
+
+			_, err = service.Transfer(context.Background(), hotAddress, otherAddress, 200)
+			assert.ErrorIs(t, err, transfer.ErrInsufficientBalance)
+			assert.Equal(t, "insufficient_balance", transfer.ErrorCode(err))
+
+			_, err = service.Reverse(context.Background(), result.Transfer.ID, "refund", false)
+			assert.ErrorIs(t, err, transfer.ErrInsufficientBalanceToReverse)
+
+			_, err = service.Reverse(context.Background(), result.Transfer.ID, "refund", true)
+			assert.NoError(t, err)
+
+			wallet, err := store.GetWallet(context.Background(), hotAddress)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(-300), wallet.Balance)
+
+			AssertReconciled(t)
+		})
+	}
+}
+
+func TestConcurrentTransfersFromHotWallet(t *testing.T) {
+	hotAddress := "0x0000000000000000000000000000000000000001"
+
+	for _, locking := range lockingStrategies {
+		t.Run(string(locking), func(t *testing.T) {
+			SetUpDatabase(t, hotAddress, 1000, "", 0)
+			assert.NoError(t, ledger.ConfigureShards(testDB, []string{hotAddress}, 8))
+
+			mutation := CreateLockingResolver(locking)
+
+			var wg sync.WaitGroup
+			errs := make([]error, 40)
+
+			for i := range errs {
+				wg.Add(1)
+
+				go func(i int) {
+					defer wg.Done()
+
+					recipient := FundedAddress(i % 4)
+					_, errs[i] = mutation.Transfer(context.Background(), hotAddress, recipient, 30)
+				}(i)
+			}
+
+			wg.Wait()
+
+			succeeded := int64(0)
+			for _, err := range errs {
+				if err == nil {
+					succeeded++
+				} else {
+					assert.EqualError(t, err, transfer.ErrInsufficientBalance.Error())
+				}
+			}
+
+			assert.Equal(t, int64(33), succeeded, "every transfer the balance covers succeeds")
+
+			wallet, err := transfer.NewGormStore(testDB, testStoreOptions).GetWallet(context.Background(), hotAddress)
+			assert.NoError(t, err)
+			assert.Equal(t, 1000-30*succeeded, wallet.Balance)
+
+			var negative int64
+			testDB.Model(&db.WalletShard{}).Where("balance < 0").Count(&negative)
+			assert.Zero(t, negative)
+
+			AssertReconciled(t)
+		})
+	}
+}
+
+func FundedAddress(i int) string {
+	return fmt.Sprintf("0x%040x", 0x100+i)
+}
@@ -0,0 +1,206 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/graph"
+	"github.com/dominika232323/token-transfer-api/internal/config"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/health"
+	"github.com/dominika232323/token-transfer-api/internal/queue"
+	"github.com/dominika232323/token-transfer-api/internal/server"
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestShutdownDrainsTransfersInFlight(t *testing.T) {
+	const senders = 8
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	RestartDatabase()
+	assert.NoError(t, CreateWallet(t, recipientAddress, 0))
+
+	for i := range senders {
+		assert.NoError(t, CreateWallet(t, FundedAddress(i), 1_000_000))
+		for range 20 {
+			_, err := queue.Submit(testDB, FundedAddress(i), recipientAddress, 1)
+			assert.NoError(t, err)
+		}
+	}
+
+	// The shutdown closes the pool it is given, so the server gets its own.
+	database := OpenTestDBPool(t)
+
+	workers := health.NewWorkers()
+	pool := queue.NewPool(database, testStoreOptions, transfer.Options{})
+	pool.Workers = 2
+	pool.BatchSize = 5
+	pool.PollInterval = 10 * time.Millisecond
+	workers.Go("queue", pool.Run)
+
+	srv := NewGraphQLServer(&graph.Resolver{
+		DB:        database,
+		Transfers: transfer.NewService(transfer.NewGormStore(database, testStoreOptions), transfer.Options{}),
+	})
+
+	// Once armed, the handler holds the next requests until the signal has
+	// been sent, so they are certainly in flight during the shutdown.
+	var armed atomic.Bool
+	var holding atomic.Int64
+	release := make(chan struct{})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if armed.Load() && holding.Add(1) <= senders {
+			<-release
+		}
+
+		srv.ServeHTTP(w, r)
+	})
+
+	api := server.New(handler, workers, database, config.Server{ShutdownTimeout: 10 * time.Second})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- api.Run(ctx, listener) }()
+
+	var succeeded, drained atomic.Int64
+	var signalled atomic.Bool
+	var load sync.WaitGroup
+
+	for i := range 2 * senders {
+		load.Add(1)
+		go func() {
+			defer load.Done()
+
+			query := fmt.Sprintf(`mutation { transfer(from_address: "%s", to_address: "%s", amount: 1) }`,
+				FundedAddress(i%senders), recipientAddress)
+
+			for {
+				ok, err := PostTransfer("http://"+listener.Addr().String()+"/query", query)
+				if err != nil {
+					// The server has stopped accepting requests.
+					return
+				}
+
+				if ok {
+					succeeded.Add(1)
+					if signalled.Load() {
+						drained.Add(1)
+					}
+				}
+			}
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return succeeded.Load() >= 50 }, 10*time.Second, time.Millisecond)
+
+	armed.Store(true)
+	assert.Eventually(t, func() bool { return holding.Load() >= senders }, 10*time.Second, time.Millisecond)
+
+	signalled.Store(true)
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(15 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+
+	load.Wait()
+
+	assert.GreaterOrEqual(t, drained.Load(), int64(senders), "requests in flight when the signal arrived should have completed")
+
+	var requests []db.TransferRequest
+	assert.NoError(t, testDB.Find(&requests).Error)
+
+	processed := int64(0)
+	for _, request := range requests {
+		assert.NotEqual(t, queue.StatusFailed, request.Status, request.Error)
+
+		if request.Status == queue.StatusCompleted {
+			processed++
+			assert.NotNil(t, request.TransferID)
+		}
+	}
+
+	var transfers int64
+	assert.NoError(t, testDB.Model(&db.Transfer{}).Count(&transfers).Error)
+	assert.Equal(t, succeeded.Load()+processed, transfers, "every committed transfer should have been reported")
+
+	var total int64
+	assert.NoError(t, testDB.Model(&db.Wallet{}).Select("SUM(balance)").Scan(&total).Error)
+	assert.Equal(t, int64(senders*1_000_000), total)
+
+	var recipient db.Wallet
+	assert.NoError(t, testDB.Where("address = ?", recipientAddress).First(&recipient).Error)
+	assert.Equal(t, transfers, recipient.Balance)
+
+	AssertReconciled(t)
+}
+
+// PostTransfer posts a transfer mutation and reports whether it succeeded.
+// It returns an error only when the request could not be made.
+func PostTransfer(url string, query string) (bool, error) {
+	body, _ := json.Marshal(map[string]string{"query": query})
+
+	response, err := http.Post(url, "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	var result graphQLResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return false, nil
+	}
+
+	return len(result.Errors) == 0 && string(result.Data) != "null", nil
+}
+
+// OpenTestDBPool opens a second connection pool to the test database.
+func OpenTestDBPool(t *testing.T) *gorm.DB {
+	var database *gorm.DB
+	var err error
+
+	if sqliteDir != "" {
+		database, err = db.OpenSQLite(filepath.Join(sqliteDir, fmt.Sprintf("test-%d.db", sqliteDatabases)))
+	} else {
+		var cfg *config.Config
+		if cfg, err = config.Load(); err == nil {
+			database, err = db.Connect(context.Background(), cfg.Database)
+		}
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return database
+}
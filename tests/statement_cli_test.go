@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/cli"
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/migrate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatementExportLeavesNoFileWhenItFails(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "statement.csv")
+
+	// The database is never migrated, so the export cannot connect.
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("SQLITE_PATH", filepath.Join(dir, "unmigrated.db"))
+
+	err := cli.Run([]string{"statement", "-address", "0x0000000000000000000000000000000000000001", "-from", "2024-01-01", "-to", "2024-12-31", "-o", output})
+	assert.ErrorIs(t, err, migrate.ErrNotMigrated)
+
+	_, err = os.Stat(output)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotContains(t, entry.Name(), "statement.csv")
+	}
+}
+
+func TestStatementExportKeepsExistingFileWhenItFails(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "statement.csv")
+	assert.NoError(t, os.WriteFile(output, []byte("previous statement\n"), 0o644))
+
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("SQLITE_PATH", filepath.Join(dir, "unmigrated.db"))
+
+	err := cli.Run([]string{"statement", "-address", "0x0000000000000000000000000000000000000001", "-from", "2024-01-01", "-to", "2024-12-31", "-o", output})
+	assert.ErrorIs(t, err, migrate.ErrNotMigrated)
+
+	content, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Equal(t, "previous statement\n", string(content))
+}
+
+func TestStatementExportWritesFile(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "statement.csv")
+	path := filepath.Join(dir, "token-transfer.db")
+
+	database, err := db.OpenSQLite(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = migrate.Up(database)
+	assert.NoError(t, err)
+
+	_, err = migrate.Seed(database)
+	assert.NoError(t, err)
+
+	if sqlDB, err := database.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("SQLITE_PATH", path)
+
+	err = cli.Run([]string{"statement", "-address", migrate.GenesisAddress, "-from", "2000-01-01", "-to", "2100-01-01", "-o", output})
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, content)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	for _, entry := range entries {
+		assert.False(t, strings.HasSuffix(entry.Name(), ".tmp"), "temporary file %s was left behind", entry.Name())
+	}
+}
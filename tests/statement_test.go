@@ -0,0 +1,90 @@
+//go:build integration
+
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/statement"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatementCSV(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpHistory(t, senderAddress, recipientAddress)
+
+	var out bytes.Buffer
+	err := statement.Write(testDB, &out, senderAddress,
+		MustParseTime(t, "2025-01-15T00:00:00Z"), MustParseTime(t, "2025-03-01T00:00:00Z"), statement.FormatCSV)
+	assert.NoError(t, err)
+
+	assert.Equal(t, strings.Join([]string{
+		"type,at,entry_id,kind,transfer_id,counterparty,amount,balance",
+		"opening_balance,2025-01-15T00:00:00Z,,,,,0,1000",
+		"movement,2025-01-31T12:00:00Z,3,transfer,1," + recipientAddress + ",-200,800",
+		"movement,2025-02-15T00:00:00Z,4,transfer,2," + recipientAddress + ",-50,750",
+		"closing_balance,2025-03-01T00:00:00Z,,,,,0,750",
+		"",
+	}, "\n"), out.String())
+}
+
+func TestStatementJSONL(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpHistory(t, senderAddress, recipientAddress)
+
+	var out bytes.Buffer
+	err := statement.Write(testDB, &out, recipientAddress,
+		MustParseTime(t, "2025-02-01T00:00:00Z"), MustParseTime(t, "2025-03-01T00:00:00Z"), statement.FormatJSONL)
+	assert.NoError(t, err)
+
+	var lines []statement.Line
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var line statement.Line
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+
+	if assert.Len(t, lines, 3) {
+		assert.Equal(t, statement.LineOpening, lines[0].Type)
+		assert.Equal(t, int64(300), lines[0].Balance)
+		assert.Equal(t, senderAddress, lines[1].Counterparty)
+		assert.Equal(t, int64(50), lines[1].Amount)
+		assert.Equal(t, statement.LineClosing, lines[2].Type)
+		assert.Equal(t, int64(350), lines[2].Balance)
+	}
+}
+
+func TestStatementEndpoint(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpHistory(t, senderAddress, recipientAddress)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /statements/{address}", statement.Handler(testDB))
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+		"/statements/"+senderAddress+"?from=2025-01-01&to=2025-02-01&format=text", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "Statement for "+senderAddress)
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+		"/statements/0x0000000000000000000000000000000000000009?from=2025-01-01&to=2025-02-01", nil))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
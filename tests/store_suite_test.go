@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
+	"github.com/stretchr/testify/assert"
+)
+
+// StoreBackend is a transfer.Store implementation the store suite runs
+// against. The integration build adds the database backend.
+type StoreBackend struct {
+	Name string
+
+	// SetUp returns a store holding the sender's wallet and, unless
+	// recipientAddress is empty, the recipient's.
+	SetUp func(t *testing.T, senderAddress string, senderBalance int64, recipientAddress string, recipientBalance int64) transfer.Store
+
+	// Check asserts the invariants the backend keeps beyond its balances.
+	Check func(t *testing.T)
+}
+
+var storeBackends = []StoreBackend{
+	{
+		Name: "memory",
+		SetUp: func(t *testing.T, senderAddress string, senderBalance int64, recipientAddress string, recipientBalance int64) transfer.Store {
+			store, _ := SetUpMemoryStore(senderAddress, senderBalance, recipientAddress, recipientBalance)
+			return store
+		},
+		Check: func(t *testing.T) {},
+	},
+}
+
+// RunStoreSuite runs test against every backend.
+func RunStoreSuite(t *testing.T, test func(t *testing.T, backend StoreBackend)) {
+	for _, backend := range storeBackends {
+		t.Run(backend.Name, func(t *testing.T) {
+			test(t, backend)
+		})
+	}
+}
+
+func TestConcurrentTransfers(t *testing.T) {
+	RunStoreSuite(t, testConcurrentTransfers)
+}
+
+func TestConcurrentTransfers_MultipleRuns(t *testing.T) {
+	RunStoreSuite(t, func(t *testing.T, backend StoreBackend) {
+		for i := 0; i < 100; i++ {
+			t.Run(fmt.Sprintf("Run-%d", i), func(t *testing.T) {
+				testConcurrentTransfers(t, backend)
+			})
+		}
+	})
+}
+
+func testConcurrentTransfers(t *testing.T, backend StoreBackend) {
+	wallet1Address := "0x0000000000000000000000000000000000000001"
+	wallet2Address := "0x0000000000000000000000000000000000000002"
+
+	store := backend.SetUp(t, wallet1Address, 10, wallet2Address, 10)
+	service := transfer.NewService(store, transfer.Options{})
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	start := make(chan struct{})
+
+	results := make([]error, 3)
+	transfers := []int64{-4, -7, 1}
+
+	for i, amount := range transfers {
+		go func(i int, amount int64) {
+			defer wg.Done()
+
+			<-start
+
+			if amount < 0 {
+				_, results[i] = service.Transfer(context.Background(), wallet1Address, wallet2Address, -1*amount)
+			} else {
+				_, results[i] = service.Transfer(context.Background(), wallet2Address, wallet1Address, amount)
+			}
+		}(i, amount)
+	}
+
+	close(start)
+	wg.Wait()
+
+	var wallet1Received int64
+	var wallet2Received int64
+
+	for i, err := range results {
+		if err == nil {
+			if transfers[i] < 0 {
+				wallet2Received += -1 * transfers[i]
+			} else {
+				wallet1Received += transfers[i]
+			}
+		}
+	}
+
+	wallet1 := StoreBalance(t, store, wallet1Address)
+	wallet2 := StoreBalance(t, store, wallet2Address)
+
+	assert.Equal(t, 10-wallet2Received+wallet1Received, wallet1)
+	assert.Equal(t, 10-wallet1Received+wallet2Received, wallet2)
+
+	assert.GreaterOrEqual(t, wallet1, int64(0))
+	assert.GreaterOrEqual(t, wallet2, int64(0))
+
+	backend.Check(t)
+}
+
+func TestBidirectionalConcurrentTransfers(t *testing.T) {
+	RunStoreSuite(t, func(t *testing.T, backend StoreBackend) {
+		walletA := "0x0000000000000000000000000000000000000001"
+		walletB := "0x0000000000000000000000000000000000000002"
+
+		store := backend.SetUp(t, walletA, 1000, walletB, 1000)
+		service := transfer.NewService(store, transfer.Options{})
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+
+		start := make(chan struct{})
+
+		var err1, err2 error
+
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err1 = service.Transfer(context.Background(), walletA, walletB, 100)
+		}()
+
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err2 = service.Transfer(context.Background(), walletB, walletA, 150)
+		}()
+
+		close(start)
+		wg.Wait()
+
+		if err1 != nil {
+			assert.NotContains(t, err1.Error(), "deadlock")
+		}
+		if err2 != nil {
+			assert.NotContains(t, err2.Error(), "deadlock")
+		}
+
+		a := StoreBalance(t, store, walletA)
+		b := StoreBalance(t, store, walletB)
+
+		assert.Equal(t, int64(2000), a+b, "Total balance should remain constant")
+		assert.Equal(t, a, int64(1050))
+		assert.Equal(t, b, int64(950))
+
+		backend.Check(t)
+	})
+}
+
+func TestConcurrentWalletCreation(t *testing.T) {
+	RunStoreSuite(t, func(t *testing.T, backend StoreBackend) {
+		senderAddress := "0x0000000000000000000000000000000000000001"
+		recipientAddress := "0x0000000000000000000000000000000000000002"
+
+		store := backend.SetUp(t, senderAddress, 1000, "", 0)
+		service := transfer.NewService(store, transfer.Options{})
+
+		var wg sync.WaitGroup
+		numTransfers := 5
+		wg.Add(numTransfers)
+		start := make(chan struct{})
+
+		transferAmount := int64(100)
+		errors := make([]error, numTransfers)
+
+		for i := 0; i < numTransfers; i++ {
+			go func(i int) {
+				defer wg.Done()
+
+				<-start
+
+				_, err := service.Transfer(context.Background(), senderAddress, recipientAddress, transferAmount)
+				errors[i] = err
+			}(i)
+		}
+
+		close(start)
+		wg.Wait()
+
+		successfulTransfers := 0
+
+		for _, err := range errors {
+			if err == nil {
+				successfulTransfers++
+			}
+		}
+
+		assert.Greater(t, successfulTransfers, 0, "At least one transfer should succeed")
+
+		_, err := store.GetWallet(context.Background(), recipientAddress)
+		assert.NoError(t, err, "New wallet should exist")
+
+		expectedRecipientBalance := int64(successfulTransfers) * transferAmount
+		AssertStoreBalance(t, store, recipientAddress, expectedRecipientBalance)
+		AssertStoreBalance(t, store, senderAddress, 1000-expectedRecipientBalance)
+
+		backend.Check(t)
+	})
+}
+
+func AssertStoreBalance(t *testing.T, store transfer.Store, address string, expected int64) {
+	assert.Equal(t, expected, StoreBalance(t, store, address), "balance of %s", address)
+}
+
+// StoreBalance returns the balance of the wallet at address, or 0 after
+// failing the test if there is no such wallet.
+func StoreBalance(t *testing.T, store transfer.Store, address string) int64 {
+	wallet, err := store.GetWallet(context.Background(), address)
+	if !assert.NoError(t, err) {
+		return 0
+	}
+
+	return wallet.Balance
+}
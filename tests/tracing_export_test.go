@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/config"
+	"github.com/dominika232323/token-transfer-api/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+)
+
+func TestFileTraceExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+
+	shutdown, err := tracing.Setup(context.Background(), config.Tracing{
+		Exporter:    "file",
+		File:        path,
+		SampleRatio: 1,
+		ServiceName: "token-transfer-test",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, span := tracing.Tracer().Start(context.Background(), "offline span")
+	span.End()
+
+	assert.NoError(t, shutdown(context.Background()))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `"Name":"offline span"`)
+	assert.Contains(t, string(content), "token-transfer-test")
+}
@@ -0,0 +1,127 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dominika232323/token-transfer-api/internal/config"
+	"github.com/dominika232323/token-transfer-api/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+func TestTracingFollowsRequestThroughDatabase(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	SetUpDatabase(t, senderAddress, 100, recipientAddress, 0)
+	recorder := SetUpTracing(t)
+
+	srv := NewGraphQLServer(CreateQueueResolver())
+	srv.Use(tracing.GraphQL{})
+
+	body := `{"query": "mutation Pay { transfer(from_address: \"` + senderAddress + `\", to_address: \"` + recipientAddress + `\", amount: 10) }"}`
+	request := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	response := httptest.NewRecorder()
+	tracing.Middleware(srv).ServeHTTP(response, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	statements := 0
+
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String(),
+			"span %s should continue the caller's trace", span.Name())
+
+		if strings.HasPrefix(span.Name(), "SQL ") {
+			statements++
+			assert.True(t, hasAttribute(span, "db.query.text"), "span %s should hold its statement", span.Name())
+			continue
+		}
+
+		spans[span.Name()] = span
+	}
+
+	assert.NotZero(t, statements)
+
+	for child, parent := range map[string]string{
+		"mutation Pay":      "HTTP POST",
+		"Mutation.transfer": "mutation Pay",
+		"Transfer":          "Mutation.transfer",
+	} {
+		if assert.Contains(t, spans, child) && assert.Contains(t, spans, parent) {
+			assert.Equal(t, spans[parent].SpanContext().SpanID(), spans[child].Parent().SpanID(),
+				"%s should be a child of %s", child, parent)
+		}
+	}
+
+	if assert.Contains(t, spans, "HTTP POST") {
+		assert.Equal(t, "00f067aa0ba902b7", spans["HTTP POST"].Parent().SpanID().String())
+	}
+}
+
+func TestTracingRecordsFailedTransfers(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 100, recipientAddress, 0)
+	recorder := SetUpTracing(t)
+
+	ctx, span := tracing.Tracer().Start(context.Background(), "test")
+	_, err := mutation.Transfer(ctx, senderAddress, recipientAddress, 1000)
+	span.End()
+
+	assert.Error(t, err)
+
+	for _, span := range recorder.Ended() {
+		if span.Name() == "Transfer" {
+			assert.Equal(t, "Error", span.Status().Code.String())
+			assert.Equal(t, "Insufficient balance", span.Status().Description)
+			return
+		}
+	}
+
+	t.Fatal("no Transfer span was recorded")
+}
+
+// SetUpTracing records the spans of the test in memory and traces the SQL
+// statements of testDB.
+func SetUpTracing(t *testing.T) *tracetest.SpanRecorder {
+	_, err := tracing.Setup(context.Background(), config.Tracing{Exporter: "none"})
+	assert.NoError(t, err)
+
+	if err := testDB.Use(tracing.GormPlugin{}); err != nil && !errors.Is(err, gorm.ErrRegistered) {
+		t.Fatal(err)
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func hasAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) bool {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
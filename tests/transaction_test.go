@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTransactionRetriesSerializationFailures(t *testing.T) {
+	database := SetUpRetryTest(t, 3)
+	before := db.TransactionRetryStats()
+
+	attempts := 0
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		attempts++
+
+		switch attempts {
+		case 1:
+			return &pgconn.PgError{Code: db.CodeSerializationFailure}
+		case 2:
+			return fmt.Errorf("failed to lock wallet: %w", &pgconn.PgError{Code: db.CodeDeadlockDetected})
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	after := db.TransactionRetryStats()
+	assert.Equal(t, int64(1), after.Retried[db.CodeSerializationFailure]-before.Retried[db.CodeSerializationFailure])
+	assert.Equal(t, int64(1), after.Retried[db.CodeDeadlockDetected]-before.Retried[db.CodeDeadlockDetected])
+}
+
+func TestTransactionGivesUpAfterMaxRetries(t *testing.T) {
+	database := SetUpRetryTest(t, 2)
+	before := db.TransactionRetryStats()
+
+	attempts := 0
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		attempts++
+		return &pgconn.PgError{Code: db.CodeSerializationFailure}
+	})
+
+	var pgErr *pgconn.PgError
+	assert.ErrorAs(t, err, &pgErr)
+	assert.ErrorContains(t, err, "transaction failed after 2 retries")
+	assert.Equal(t, 3, attempts)
+
+	after := db.TransactionRetryStats()
+	assert.Equal(t, int64(2), after.Retried[db.CodeSerializationFailure]-before.Retried[db.CodeSerializationFailure])
+	assert.Equal(t, int64(1), after.Exhausted[db.CodeSerializationFailure]-before.Exhausted[db.CodeSerializationFailure])
+}
+
+func TestTransactionDoesNotRetryOtherErrors(t *testing.T) {
+	database := SetUpRetryTest(t, 3)
+	failure := errors.New("Insufficient balance")
+
+	attempts := 0
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		attempts++
+		return failure
+	})
+
+	assert.Equal(t, failure, err)
+	assert.Equal(t, 1, attempts)
+
+	// Wallets are created with ON CONFLICT DO NOTHING, so a unique violation
+	// is a real conflict that running the transaction again cannot resolve.
+	attempts = 0
+	err = db.Transaction(database, func(tx *gorm.DB) error {
+		attempts++
+		return &pgconn.PgError{Code: "23505"}
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestTransactionDoesNotRetryNestedTransactions(t *testing.T) {
+	database := SetUpRetryTest(t, 3)
+
+	outer, inner := 0, 0
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		outer++
+
+		err := db.Transaction(tx, func(tx *gorm.DB) error {
+			inner++
+			return &pgconn.PgError{Code: db.CodeDeadlockDetected}
+		})
+
+		if outer < 2 {
+			return err
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, outer, "the outermost transaction should be retried")
+	assert.Equal(t, 2, inner, "the savepoint should not be retried on its own")
+}
+
+func TestTransactionStopsRetryingWhenContextIsDone(t *testing.T) {
+	database := SetUpRetryTest(t, 100)
+	db.SetRetryPolicy(db.RetryPolicy{MaxRetries: 100, BaseDelay: time.Second, MaxDelay: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	err := db.Transaction(database.WithContext(ctx), func(tx *gorm.DB) error {
+		attempts++
+		return &pgconn.PgError{Code: db.CodeSerializationFailure}
+	})
+
+	assert.Error(t, err)
+	assert.Less(t, attempts, 100)
+}
+
+// SetUpRetryTest opens an empty SQLite database and retries transactions up
+// to maxRetries times without waiting, restoring the default policy when the
+// test ends.
+func SetUpRetryTest(t *testing.T, maxRetries int) *gorm.DB {
+	database, err := db.OpenSQLite(filepath.Join(t.TempDir(), "retry.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	db.SetRetryPolicy(db.RetryPolicy{MaxRetries: maxRetries})
+
+	t.Cleanup(func() {
+		db.SetRetryPolicy(db.DefaultRetryPolicy)
+
+		if sqlDB, err := database.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return database
+}
@@ -1,39 +1,71 @@
+//go:build integration
+
 package tests
 
 import (
 	"context"
 	"fmt"
 	"github.com/dominika232323/token-transfer-api/graph"
+	"github.com/dominika232323/token-transfer-api/internal/config"
 	"github.com/dominika232323/token-transfer-api/internal/db"
-	"github.com/joho/godotenv"
+	"github.com/dominika232323/token-transfer-api/internal/ledger"
+	"github.com/dominika232323/token-transfer-api/internal/migrate"
+	"github.com/dominika232323/token-transfer-api/internal/transfer"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"log"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 )
 
 var testDB *gorm.DB
 
+// sqliteDir holds a fresh database file per RestartDatabase when the suite
+// runs with DB_DRIVER=sqlite.
+var sqliteDir string
+
+// testStoreOptions selects the locking strategy and isolation level under
+// test with DB_LOCKING and DB_ISOLATION.
+var testStoreOptions transfer.StoreOptions
+
 func TestMain(m *testing.M) {
-	_ = godotenv.Load(".env")
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("invalid test configuration: %v", err)
+	}
 
-	dsn := "host=" + os.Getenv("POSTGRES_HOST") +
-		" user=" + os.Getenv("POSTGRES_USER") +
-		" password=" + os.Getenv("POSTGRES_PASSWORD") +
-		" dbname=" + os.Getenv("POSTGRES_DB") +
-		" port=" + os.Getenv("POSTGRES_PORT") +
-		" sslmode=disable"
+	testStoreOptions = transfer.StoreOptions{
+		Locking:   transfer.Locking(cfg.Database.Locking),
+		Isolation: db.IsolationLevel(cfg.Database.Isolation),
+	}
+
+	if cfg.Database.Driver == db.DriverSQLite {
+		sqliteDir, err = os.MkdirTemp("", "token-transfer-tests")
+		if err != nil {
+			log.Fatalf("failed to create test db directory: %v", err)
+		}
+
+		testDB, err = OpenSQLiteTestDB()
+	} else {
+		testDB, err = db.Connect(context.Background(), cfg.Database)
+	}
 
-	var err error
-	testDB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("failed to connect to test db: %v", err)
 	}
 
+	if _, err := migrate.Up(testDB); err != nil {
+		log.Fatalf("failed to migrate test db: %v", err)
+	}
+
 	code := m.Run()
+
+	if sqliteDir != "" {
+		_ = os.RemoveAll(sqliteDir)
+	}
+
 	os.Exit(code)
 }
 
@@ -45,7 +77,7 @@ func TestSuccessfulTransfer(t *testing.T) {
 	newBalance, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
 
 	assert.NoError(t, err)
-	assert.Equal(t, int32(800), newBalance)
+	assert.Equal(t, int64(800), newBalance)
 
 	var recipient db.Wallet
 	testDB.First(&recipient, "address = ?", recipientAddress)
@@ -75,7 +107,7 @@ func TestTransferWithZeroAmount(t *testing.T) {
 	newBalance, err := mutation.Transfer(context.Background(), senderAddress, senderAddress, 0)
 
 	assert.NoError(t, err)
-	assert.Equal(t, int32(1000), newBalance)
+	assert.Equal(t, int64(1000), newBalance)
 
 	var recipient db.Wallet
 	testDB.First(&recipient, "address = ?", recipientAddress)
@@ -105,7 +137,7 @@ func TestTransferToUnknownRecipient(t *testing.T) {
 	newBalance, err := mutation.Transfer(context.Background(), senderAddress, unknowRecipientAddress, 200)
 
 	assert.NoError(t, err)
-	assert.Equal(t, int32(800), newBalance)
+	assert.Equal(t, int64(800), newBalance)
 
 	var recipient db.Wallet
 	testDB.First(&recipient, "address = ?", unknowRecipientAddress)
@@ -116,6 +148,44 @@ func TestTransferToUnknownRecipient(t *testing.T) {
 	assert.Equal(t, int64(800), sender.Balance)
 }
 
+func TestConcurrentTransfersToUnknownRecipient(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	unknownRecipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, "", 0)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+
+	for i := range errs {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			if i%2 == 0 {
+				_, errs[i] = mutation.Transfer(context.Background(), senderAddress, unknownRecipientAddress, 10)
+			} else {
+				errs[i] = ledger.Issue(testDB, unknownRecipientAddress, 10)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err, "creating the same wallet concurrently must not fail")
+	}
+
+	var wallets int64
+	testDB.Model(&db.Wallet{}).Where("address = ?", unknownRecipientAddress).Count(&wallets)
+	assert.Equal(t, int64(1), wallets)
+
+	assert.Equal(t, int64(900), FindWallet(t, senderAddress).Balance)
+	assert.Equal(t, int64(200), FindWallet(t, unknownRecipientAddress).Balance)
+	AssertReconciled(t)
+}
+
 func TestTransferFromUnknownSender(t *testing.T) {
 	senderAddress := "0x0000000000000000000000000000000000000001"
 	recipientAddress := "0x0000000000000000000000000000000000000002"
@@ -135,7 +205,7 @@ func TestTransferToSelf(t *testing.T) {
 	newBalance, err := mutation.Transfer(context.Background(), senderAddress, senderAddress, 200)
 
 	assert.NoError(t, err)
-	assert.Equal(t, int32(1000), newBalance)
+	assert.Equal(t, int64(1000), newBalance)
 
 	var sender db.Wallet
 	testDB.First(&sender, "address = ?", senderAddress)
@@ -159,7 +229,7 @@ func TestTransferWithZeroAmountToSelf(t *testing.T) {
 	newBalance, err := mutation.Transfer(context.Background(), senderAddress, senderAddress, 0)
 
 	assert.NoError(t, err)
-	assert.Equal(t, int32(1000), newBalance)
+	assert.Equal(t, int64(1000), newBalance)
 
 	var sender db.Wallet
 	testDB.First(&sender, "address = ?", senderAddress)
@@ -186,199 +256,87 @@ func TestTransferToNonExistentSelf(t *testing.T) {
 	assert.Contains(t, err.Error(), "Insufficient balance")
 }
 
-func TestConcurrentTransfers(t *testing.T) {
-	wallet1Address := "0x0000000000000000000000000000000000000001"
-	wallet2Address := "0x0000000000000000000000000000000000000002"
-
-	_, mutation := SetUpDatabase(t, wallet1Address, 10, wallet2Address, 10)
-
-	var wg sync.WaitGroup
-	wg.Add(3)
-	start := make(chan struct{})
-
-	results := make([]error, 3)
-	transfers := []int32{-4, -7, 1}
-
-	for i, amount := range transfers {
-		go func(i int, amount int32) {
-			defer wg.Done()
-
-			<-start
-
-			if amount < 0 {
-				_, err := mutation.Transfer(context.Background(), wallet1Address, wallet2Address, -1*amount)
-				results[i] = err
-			} else {
-				_, err := mutation.Transfer(context.Background(), wallet2Address, wallet1Address, amount)
-				results[i] = err
-			}
-
-		}(i, amount)
-	}
-
-	close(start)
-	wg.Wait()
-
-	var wallet1 db.Wallet
-	testDB.First(&wallet1, "address = ?", wallet1Address)
+func init() {
+	storeBackends = append(storeBackends, StoreBackend{
+		Name: "database",
+		SetUp: func(t *testing.T, senderAddress string, senderBalance int64, recipientAddress string, recipientBalance int64) transfer.Store {
+			SetUpDatabase(t, senderAddress, senderBalance, recipientAddress, recipientBalance)
+			return transfer.NewGormStore(testDB, testStoreOptions)
+		},
+		Check: AssertReconciled,
+	})
+}
 
-	var wallet2 db.Wallet
-	testDB.First(&wallet2, "address = ?", wallet2Address)
+func SetUpDatabase(t *testing.T, senderAddress string, senderBalance int64, recipientAddress string, recipientBalance int64) (error, graph.MutationResolver) {
+	RestartDatabase()
 
-	var wallet1Received int32
-	var wallet2Received int32
+	err := CreateWallet(t, senderAddress, senderBalance)
+	assert.NoError(t, err)
 
-	for i, err := range results {
-		if err == nil {
-			if transfers[i] < 0 {
-				wallet2Received += -1 * transfers[i]
-			} else {
-				wallet1Received += transfers[i]
-			}
-		}
+	if recipientAddress != "" {
+		err = CreateWallet(t, recipientAddress, recipientBalance)
+		assert.NoError(t, err)
 	}
 
-	expectedFinalWallet1Balance := 10 - int64(wallet2Received) + int64(wallet1Received)
-	expectedFinalWallet2Balance := 10 - int64(wallet1Received) + int64(wallet2Received)
-
-	assert.Equal(t, expectedFinalWallet1Balance, wallet1.Balance)
-	assert.Equal(t, expectedFinalWallet2Balance, wallet2.Balance)
-
-	assert.GreaterOrEqual(t, wallet1.Balance, int64(0))
-	assert.GreaterOrEqual(t, wallet2.Balance, int64(0))
-}
-
-func TestConcurrentTransfers_MultipleRuns(t *testing.T) {
-	for i := 0; i < 100; i++ {
-		t.Run(fmt.Sprintf("Run-%d", i), TestConcurrentTransfers)
-	}
+	mutation := CreateMutationResolver()
+	return err, mutation
 }
 
-func TestBidirectionalConcurrentTransfers(t *testing.T) {
-	walletA := "0x0000000000000000000000000000000000000001"
-	walletB := "0x0000000000000000000000000000000000000002"
-
-	_, mutation := SetUpDatabase(t, walletA, 1000, walletB, 1000)
-
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	start := make(chan struct{})
-
-	var err1, err2 error
-
-	go func() {
-		defer wg.Done()
-		<-start
-		_, err1 = mutation.Transfer(context.Background(), walletA, walletB, 100)
-	}()
-
-	go func() {
-		defer wg.Done()
-		<-start
-		_, err2 = mutation.Transfer(context.Background(), walletB, walletA, 150)
-	}()
-
-	close(start)
-	wg.Wait()
-
-	if err1 != nil {
-		assert.NotContains(t, err1.Error(), "deadlock")
-	}
-	if err2 != nil {
-		assert.NotContains(t, err2.Error(), "deadlock")
+func RestartDatabase() *gorm.DB {
+	if sqliteDir != "" {
+		return RestartSQLiteDatabase()
 	}
 
-	var a db.Wallet
-	var b db.Wallet
-
-	testDB.First(&a, "address = ?", walletA)
-	testDB.First(&b, "address = ?", walletB)
-
-	total := a.Balance + b.Balance
-
-	assert.Equal(t, int64(2000), total, "Total balance should remain constant")
-	assert.Equal(t, a.Balance, int64(1050))
-	assert.Equal(t, b.Balance, int64(950))
+	return testDB.Exec("TRUNCATE TABLE wallets, wallet_shards, transfers, audit_log, journal_entries, journal_lines, balance_checkpoints, checkpoint_balances, events, event_balances, event_transfers, outbox, webhook_endpoints, webhook_deliveries, webhook_attempts, transfer_requests RESTART IDENTITY")
 }
 
-func TestConcurrentWalletCreation(t *testing.T) {
-	senderAddress := "0x0000000000000000000000000000000000000001"
-	recipientAddress := "0x0000000000000000000000000000000000000002"
-
-	_, mutation := SetUpDatabase(t, senderAddress, 1000, "", 0)
-
-	var wg sync.WaitGroup
-	numTransfers := 5
-	wg.Add(numTransfers)
-	start := make(chan struct{})
-
-	transferAmount := int32(100)
-	errors := make([]error, numTransfers)
-
-	for i := 0; i < numTransfers; i++ {
-		go func(i int) {
-			defer wg.Done()
-
-			<-start
-
-			_, err := mutation.Transfer(context.Background(), senderAddress, recipientAddress, transferAmount)
-			errors[i] = err
-		}(i)
+// RestartSQLiteDatabase replaces testDB with an empty database file, because
+// SQLite has no TRUNCATE and the audit log triggers reject DELETE.
+func RestartSQLiteDatabase() *gorm.DB {
+	if sqlDB, err := testDB.DB(); err == nil {
+		_ = sqlDB.Close()
 	}
 
-	close(start)
-	wg.Wait()
-
-	successfulTransfers := 0
-
-	for _, err := range errors {
-		if err == nil {
-			successfulTransfers++
-		}
+	var err error
+	testDB, err = OpenSQLiteTestDB()
+	if err != nil {
+		log.Fatalf("failed to open test db: %v", err)
 	}
 
-	assert.Greater(t, successfulTransfers, 0, "At least one transfer should succeed")
-
-	var recipient db.Wallet
-	result := testDB.First(&recipient, "address = ?", recipientAddress)
-	assert.NoError(t, result.Error, "New wallet should exist")
-
-	expectedRecipientBalance := int64(successfulTransfers) * int64(transferAmount)
-	assert.Equal(t, expectedRecipientBalance, recipient.Balance)
-
-	var sender db.Wallet
-	testDB.First(&sender, "address = ?", senderAddress)
-	assert.Equal(t, int64(1000)-expectedRecipientBalance, sender.Balance)
+	return testDB
 }
 
-func SetUpDatabase(t *testing.T, senderAddress string, senderBalance int64, recipientAddress string, recipientBalance int64) (error, graph.MutationResolver) {
-	RestartDatabase()
+var sqliteDatabases int
 
-	err := CreateWallet(t, senderAddress, senderBalance)
-	assert.NoError(t, err)
+func OpenSQLiteTestDB() (*gorm.DB, error) {
+	sqliteDatabases++
 
-	if recipientAddress != "" {
-		err = CreateWallet(t, recipientAddress, recipientBalance)
-		assert.NoError(t, err)
+	database, err := db.OpenSQLite(filepath.Join(sqliteDir, fmt.Sprintf("test-%d.db", sqliteDatabases)))
+	if err != nil {
+		return nil, err
 	}
 
-	mutation := CreateMutationResolver()
-	return err, mutation
-}
+	if _, err := migrate.Up(database); err != nil {
+		return nil, err
+	}
 
-func RestartDatabase() *gorm.DB {
-	return testDB.Exec("TRUNCATE TABLE wallets RESTART IDENTITY")
+	return database, nil
 }
 
 func CreateMutationResolver() graph.MutationResolver {
-	resolver := &graph.Resolver{DB: testDB}
+	resolver := &graph.Resolver{DB: testDB, Transfers: transfer.NewService(transfer.NewGormStore(testDB, testStoreOptions), transfer.Options{})}
 	mutation := resolver.Mutation()
 	return mutation
 }
 
 func CreateWallet(t *testing.T, senderAddress string, balance int64) error {
-	err := testDB.Create(&db.Wallet{Address: senderAddress, Balance: balance}).Error
+	err := testDB.Create(&db.Wallet{Address: senderAddress}).Error
 	assert.NoError(t, err)
+
+	if err == nil && balance > 0 {
+		err = ledger.Issue(testDB, senderAddress, balance)
+		assert.NoError(t, err)
+	}
+
 	return err
 }
@@ -0,0 +1,173 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/db"
+	"github.com/dominika232323/token-transfer-api/internal/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func TestWebhookDeliveredWithSignature(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+
+	var mu sync.Mutex
+	var received []receivedWebhook
+
+	consumer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, receivedWebhook{header: r.Header.Clone(), body: body})
+		mu.Unlock()
+	}))
+	defer consumer.Close()
+
+	endpoint, err := webhook.Register(testDB, consumer.URL)
+	assert.NoError(t, err)
+
+	_, err = mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	assert.NoError(t, webhook.NewDispatcher(testDB).RunOnce(context.Background()))
+
+	if assert.Len(t, received, 1) {
+		timestamp, err := strconv.ParseInt(received[0].header.Get("X-Webhook-Timestamp"), 10, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, webhook.EventTransferCompleted, received[0].header.Get("X-Webhook-Event"))
+		assert.True(t, webhook.Verify(endpoint.Secret, timestamp, received[0].body, received[0].header.Get("X-Webhook-Signature")))
+
+		var payload webhook.TransferPayload
+		assert.NoError(t, json.Unmarshal(received[0].body, &payload))
+		assert.Equal(t, senderAddress, payload.FromAddress)
+		assert.Equal(t, recipientAddress, payload.ToAddress)
+		assert.Equal(t, int64(200), payload.Amount)
+	}
+
+	deliveries, err := webhook.Deliveries(testDB, endpoint.ID, webhook.StatusDelivered, 10)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+
+	assert.NoError(t, webhook.NewDispatcher(testDB).RunOnce(context.Background()))
+	assert.Len(t, received, 1, "delivered webhooks must not be sent again")
+}
+
+func TestWebhookRetriedThenDeadLettered(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+
+	consumer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer consumer.Close()
+
+	endpoint, err := webhook.Register(testDB, consumer.URL)
+	assert.NoError(t, err)
+
+	_, err = mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	dispatcher := webhook.NewDispatcher(testDB)
+	dispatcher.MaxAttempts = 3
+	dispatcher.BaseBackoff = 0
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, dispatcher.RunOnce(context.Background()))
+	}
+
+	deliveries, err := webhook.Deliveries(testDB, endpoint.ID, "", 10)
+	assert.NoError(t, err)
+
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, webhook.StatusDead, deliveries[0].Status)
+		assert.Equal(t, 3, deliveries[0].Attempts)
+		assert.Len(t, deliveries[0].AttemptLog, 3)
+		assert.Equal(t, http.StatusServiceUnavailable, deliveries[0].AttemptLog[0].StatusCode)
+	}
+}
+
+func TestWebhookSentOutsideTransaction(t *testing.T) {
+	senderAddress := "0x0000000000000000000000000000000000000001"
+	recipientAddress := "0x0000000000000000000000000000000000000002"
+
+	_, mutation := SetUpDatabase(t, senderAddress, 1000, recipientAddress, 100)
+
+	var writeErr error
+	var claimed db.WebhookDelivery
+
+	// The consumer writes to the delivery being sent, which would wait for the
+	// dispatcher if it held a transaction or a row lock during the send.
+	consumer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		id := r.Header.Get("X-Webhook-Id")
+		writeErr = testDB.WithContext(ctx).Exec("UPDATE webhook_deliveries SET last_error = last_error WHERE id = ?", id).Error
+		_ = testDB.First(&claimed, id).Error
+	}))
+	defer consumer.Close()
+
+	endpoint, err := webhook.Register(testDB, consumer.URL)
+	assert.NoError(t, err)
+
+	_, err = mutation.Transfer(context.Background(), senderAddress, recipientAddress, 200)
+	assert.NoError(t, err)
+
+	started := time.Now()
+	assert.NoError(t, webhook.NewDispatcher(testDB).RunOnce(context.Background()))
+
+	assert.NoError(t, writeErr)
+	assert.True(t, claimed.NextAttemptAt.After(started.Add(time.Minute)), "the delivery should be claimed while it is sent")
+
+	deliveries, err := webhook.Deliveries(testDB, endpoint.ID, webhook.StatusDelivered, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, 1, deliveries[0].Attempts)
+		assert.Len(t, deliveries[0].AttemptLog, 1)
+	}
+}
+
+func TestRotateWebhookSecretKeepsPreviousSecretDuringGracePeriod(t *testing.T) {
+	RestartDatabase()
+
+	endpoint, err := webhook.Register(testDB, "http://localhost:9999/hooks")
+	assert.NoError(t, err)
+
+	rotated, err := webhook.RotateSecret(testDB, endpoint.ID)
+	assert.NoError(t, err)
+	assert.NotEqual(t, endpoint.Secret, rotated.Secret)
+
+	body := []byte(`{"transfer_id":1}`)
+	header := webhook.Sign(rotated, 1700000000, body, *rotated.PreviousSecretExpiresAt)
+	assert.True(t, webhook.Verify(rotated.Secret, 1700000000, body, header))
+	assert.False(t, webhook.Verify(endpoint.Secret, 1700000000, body, header))
+
+	header = webhook.Sign(rotated, 1700000000, body, rotated.UpdatedAt)
+	assert.True(t, webhook.Verify(endpoint.Secret, 1700000000, body, header))
+
+	var stored db.WebhookEndpoint
+	testDB.First(&stored, endpoint.ID)
+	assert.Equal(t, rotated.Secret, stored.Secret)
+
+	_, err = webhook.RotateSecret(testDB, 42)
+	assert.ErrorIs(t, err, webhook.ErrEndpointNotFound)
+}
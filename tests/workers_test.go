@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dominika232323/token-transfer-api/internal/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkersStatus(t *testing.T) {
+	workers := health.NewWorkers()
+	stop := make(chan struct{})
+
+	for range 2 {
+		workers.Go("queue", func(ctx context.Context) { <-ctx.Done() })
+	}
+	workers.Go("webhook", func(context.Context) { <-stop })
+
+	assert.Equal(t, map[string]health.WorkerStatus{
+		"queue":   {Started: 2, Running: 2},
+		"webhook": {Started: 1, Running: 1},
+	}, workers.Status())
+
+	close(stop)
+	assert.Eventually(t, func() bool {
+		return workers.Status()["webhook"] == health.WorkerStatus{Started: 1, Running: 0}
+	}, time.Second, 10*time.Millisecond)
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer waitCancel()
+	assert.ErrorIs(t, workers.Wait(waitCtx), context.DeadlineExceeded)
+
+	workers.Stop()
+	assert.NoError(t, workers.Wait(context.Background()))
+	assert.Equal(t, health.WorkerStatus{Started: 2, Running: 0}, workers.Status()["queue"])
+}
+
+func TestLivenessHandler(t *testing.T) {
+	response := httptest.NewRecorder()
+	health.LivenessHandler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"status": "ok"}`, response.Body.String())
+}